@@ -1,22 +1,35 @@
 package main
 
 import (
+	"L0WB/internal/config"
+	orderkafka "L0WB/internal/kafka"
+	"L0WB/internal/logger"
+	"L0WB/internal/models"
+	"L0WB/internal/schema"
+	"L0WB/internal/tracing"
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/go-faker/faker/v4"
 	"github.com/segmentio/kafka-go"
-	"log"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
 	"math/rand"
+	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
-type Order struct {
+// структуры fake* описывают только генерацию тестовых данных;
+// в Kafka отправляется models.Order, проверенный по JSON Schema
+type fakeOrder struct {
 	OrderUID          string `faker:"uuid_digit" json:"order_uid"`
 	TrackNumber       string `faker:"len=13" json:"track_number"`
 	Entry             string `faker:"oneof:WBIL,WBOL,WBUL,WBAL,WBEL" json:"entry"`
-	Locale            string `faker:"oneof:en,ru,kz,by" json:"locale"`
+	Locale            string `faker:"oneof:en,ru,kk,be" json:"locale"`
 	InternalSignature string `faker:"oneof:-,identifier" json:"internal_signature"`
 	CustomerID        string `faker:"username" json:"customer_id"`
 	DeliveryService   string `faker:"oneof:Почта России,BoxBerry,CDEK,5POST,Avito" json:"delivery_service"`
@@ -25,15 +38,15 @@ type Order struct {
 	DateCreated       string `faker:"timestamp" json:"date_created"`
 	OofShard          string `faker:"oneof:1,2,3,4,5" json:"oof_shard"`
 
-	Delivery Delivery `json:"delivery"`
-	Payment  Payment  `json:"payment"`
-	Items    []Item   `json:"items"`
+	Delivery fakeDelivery `json:"delivery"`
+	Payment  fakePayment  `json:"payment"`
+	Items    []fakeItem   `json:"items"`
 }
 
-type Delivery struct {
+type fakeDelivery struct {
 	Name    string `faker:"name" json:"name"`
 	Phone   string `faker:"phone_number" json:"phone"`
-	Zip     string `faker:"word" json:"zip"`
+	Zip     string `faker:"oneof:101000,190000,050000,220030" json:"zip"`
 	City    string `faker:"word" json:"city"`
 	Address string `faker:"sentence len=3" json:"address"`
 	Region  string `faker:"word" json:"region"`
@@ -46,10 +59,10 @@ type fakedig struct {
 	Num3 float64 `faker:"amount"`
 }
 
-type Payment struct {
+type fakePayment struct {
 	TransactionNumber string `faker:"uuid_digit" json:"transaction"`
 	RequestID         string `faker:"oneof:-" json:"request_id"`
-	Currency          string `faker:"currency" json:"currency"`
+	Currency          string `faker:"oneof:RUB,USD,EUR,KZT,BYN" json:"currency"`
 	Provider          string `faker:"oneof:wbpay,alfabank,sber,tbank,vtb" json:"provider"`
 	Amount            int    `json:"amount"`
 	PaymentDT         int64  `faker:"unix_time" json:"payment_dt"`
@@ -59,7 +72,7 @@ type Payment struct {
 	CustomFee         int    `json:"custom_fee"`
 }
 
-type Item struct {
+type fakeItem struct {
 	ChrtID      int    `json:"chrt_id"`
 	TrackNumber string `faker:"len=13" json:"track_number"`
 	Price       int    `json:"price"`
@@ -86,50 +99,116 @@ func AdrToArr(adr string) []string {
 	arr[4] = strings.TrimSuffix(arr[4], " Coordinates")
 	return arr
 }
+
+// переносит сгенерированные данные в models.Order и проверяет результат по схеме заказа
+func toOrder(f fakeOrder) (models.Order, error) {
+	var order models.Order
+	raw, err := json.Marshal(f)
+	if err != nil {
+		return order, err
+	}
+	violations, err := schema.Order().Validate(raw)
+	if err != nil {
+		return order, err
+	}
+	if len(violations) > 0 {
+		return order, fmt.Errorf("%s: %s", violations[0].Path, violations[0].Message)
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&order)
+	return order, err
+}
+
+// пишет ошибку и завершает процесс
+func fatal(log *slog.Logger, msg string, err error) {
+	log.Error(msg, "error", err)
+	os.Exit(1)
+}
+
 func main() {
-	brokerAddress := "localhost:29092"
-	topic := "orders"
-
-	writer := kafka.NewWriter(kafka.WriterConfig{
-		Brokers:  []string{brokerAddress},
-		Topic:    topic,
-		Balancer: &kafka.LeastBytes{},
-	})
+	// до загрузки конфига логи пишутся в JSON с уровнем по умолчанию
+	log := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	cfg, err := new(config.AppConfig).LoadConfig()
+	if err != nil {
+		fatal(log, "failed to load config", err)
+	}
+	if log, err = logger.New(cfg.Log, os.Stdout); err != nil {
+		fatal(slog.New(slog.NewJSONHandler(os.Stdout, nil)), "failed to create logger", err)
+	}
+	slog.SetDefault(log)
+	topic := cfg.Kafka.Topic
+	for _, t := range cfg.Kafka.TopicConfigs() {
+		if t.Handler == orderkafka.HandlerOrder {
+			topic = t.Name
+			break
+		}
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing, "order-producer")
+	if err != nil {
+		fatal(log, "failed to initialize tracing", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	writer, err := orderkafka.NewWriter(cfg.Kafka, topic)
+	if err != nil {
+		fatal(log, "failed to create writer", err)
+	}
+	// партиция и смещение известны только из ответа брокера
+	writer.Completion = func(messages []kafka.Message, err error) {
+		if err != nil {
+			return
+		}
+		for _, msg := range messages {
+			log.Info("order sent", logger.KeyOrderUID, string(msg.Key), logger.KeyTopic, msg.Topic,
+				logger.KeyPartition, msg.Partition, logger.KeyOffset, msg.Offset)
+		}
+	}
 	defer writer.Close()
 
-	orders := make([]Order, numOrders)
+	fakes := make([]fakeOrder, numOrders)
 	for i := 0; i < numOrders; i++ {
-		err := faker.FakeData(&orders[i])
-		orders[i].DateCreated = time.Now().Format("2006-01-02T15:04:05Z07:00")
-		strings.ToUpper(orders[i].TrackNumber)
+		err := faker.FakeData(&fakes[i])
+		fakes[i].DateCreated = time.Now().Format("2006-01-02T15:04:05Z07:00")
+		strings.ToUpper(fakes[i].TrackNumber)
 
 		if err != nil {
-			log.Fatalf("Failed to generate fake order data: %v", err)
+			fatal(log, "failed to generate fake order data", err)
 		}
-		orders[i].Delivery = Delivery{}
-		faker.FakeData(&orders[i].Delivery)
+		fakes[i].Delivery = fakeDelivery{}
+		faker.FakeData(&fakes[i].Delivery)
 		sb := strings.Builder{}
 		sb.WriteString("+")
-		sb.WriteString(orders[i].Delivery.Phone)
-		orders[i].Delivery.Phone = sb.String()
+		sb.WriteString(fakes[i].Delivery.Phone)
+		fakes[i].Delivery.Phone = sb.String()
 		am := fakedig{}
 		faker.FakeData(&am)
 
-		orders[i].Payment = Payment{}
-		faker.FakeData(&orders[i].Payment)
-		orders[i].Payment.Amount = int(am.Num1)
-		orders[i].Payment.DeliveryCost = int(am.Num2)
-		orders[i].Payment.CustomFee = int(am.Num3)
-		orders[i].Items = []Item{}
+		fakes[i].Payment = fakePayment{}
+		faker.FakeData(&fakes[i].Payment)
+		fakes[i].Payment.Amount = int(am.Num1)
+		fakes[i].Payment.DeliveryCost = int(am.Num2)
+		fakes[i].Payment.CustomFee = int(am.Num3)
+		fakes[i].Items = []fakeItem{}
 		for j := 0; j < 3; j++ {
-			var item Item
+			var item fakeItem
 			faker.FakeData(&item)
 			item.ChrtID = rand.Intn(10000)
 			item.Price = rand.Intn(100000)
 			item.TotalPrice = item.Price * (100 - item.Sale) / 100
 			item.NmID = rand.Intn(1000)
-			orders[i].Items = append(orders[i].Items, item)
+			fakes[i].Items = append(fakes[i].Items, item)
+		}
+	}
+
+	orders := make([]models.Order, numOrders)
+	for i := range fakes {
+		order, err := toOrder(fakes[i])
+		if err != nil {
+			fatal(log.With(logger.KeyOrderUID, fakes[i].OrderUID), "generated order does not match the order contract", err)
 		}
+		orders[i] = order
 	}
 
 	chunkSize := numOrders / numGoroutines
@@ -143,27 +222,33 @@ func main() {
 		}
 
 		wg.Add(1)
-		go func(orders []Order) {
+		go func(orders []models.Order) {
 			defer wg.Done()
 			for _, order := range orders {
 				orderBytes, err := json.Marshal(order)
 				if err != nil {
-					log.Printf("Failed to marshal order: %v", err)
+					log.Error("failed to marshal order", logger.KeyOrderUID, order.OrderUID, "error", err)
 					continue
 				}
 
 				msg := kafka.Message{
 					Key:   []byte(order.OrderUID),
 					Value: orderBytes,
+					Headers: []kafka.Header{
+						{Key: orderkafka.HeaderSchemaVersion, Value: []byte(strconv.Itoa(orderkafka.CurrentSchemaVersion))},
+					},
 				}
 
-				err = writer.WriteMessages(context.Background(), msg)
+				ctx, span := tracing.Start(context.Background(), "kafka.produce "+topic, trace.WithSpanKind(trace.SpanKindProducer))
+				orderkafka.InjectTraceContext(ctx, &msg)
+				err = writer.WriteMessages(ctx, msg)
+				tracing.RecordError(span, err)
+				span.End()
 				if err != nil {
-					log.Printf("Failed to write messages: %v", err)
+					log.Error("failed to write message", logger.KeyOrderUID, order.OrderUID, "error", err)
 					continue
 				}
 
-				log.Printf("Sent order: %s", order.OrderUID)
 				time.Sleep(time.Second * 4)
 			}
 		}(orders[start:end])
@@ -171,5 +256,5 @@ func main() {
 
 	wg.Wait()
 
-	log.Println("Producer finished")
+	log.Info("producer finished", "orders", numOrders)
 }
@@ -2,18 +2,45 @@ package main
 
 import (
 	"L0WB/internal/app"
-	"log"
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func main() {
 	application := app.NewApp()
 
 	if err := application.Initialize(); err != nil {
-		log.Fatalf("Failed to initialize app: %v\n", err)
+		slog.Error("failed to initialize app", "error", err)
+		os.Exit(1)
 	}
-	if err := application.Start(); err != nil {
-		log.Fatalf("Failed to run app: %v\n", err)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- application.Start()
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		slog.Info("shutdown signal received")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if shutdownErr := application.Shutdown(shutdownCtx); shutdownErr != nil {
+		slog.Error("failed to shutdown app", "error", shutdownErr)
+	}
+	cancel()
+	if err != nil {
+		slog.Error("failed to run app", "error", err)
+		os.Exit(1)
 	}
 
-	log.Println("App finished.")
+	slog.Info("app finished")
 }
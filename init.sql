@@ -11,9 +11,20 @@ CREATE TABLE IF NOT EXISTS orders (
     shardkey VARCHAR(10),
     sm_id INT,
     date_created TIMESTAMP WITH TIME ZONE,
-    oof_shard VARCHAR(10)
+    oof_shard VARCHAR(10),
+    status VARCHAR(20) NOT NULL DEFAULT 'created',
+    version INT NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP WITH TIME ZONE
     );
 
+-- обновление существующей базы: колонки, появившиеся после первой версии схемы
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'created';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- GetLastOrders: последние заказы по date_created
+CREATE INDEX IF NOT EXISTS orders_date_created_idx ON orders (date_created DESC, order_uid) WHERE deleted_at IS NULL;
+
 CREATE TABLE IF NOT EXISTS delivery (
     order_uid VARCHAR(255) PRIMARY KEY REFERENCES orders(order_uid) ON DELETE CASCADE,
     fio VARCHAR(255),
@@ -55,6 +66,29 @@ CREATE TABLE IF NOT EXISTS items (
     status INT
 );
 
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id SERIAL PRIMARY KEY,
+    order_uid VARCHAR(255) REFERENCES orders(order_uid) ON DELETE CASCADE,
+    from_status VARCHAR(20),
+    to_status VARCHAR(20) NOT NULL,
+    reason TEXT,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS order_status_history_order_uid_idx ON order_status_history (order_uid);
+
+CREATE TABLE IF NOT EXISTS order_audit (
+    id BIGSERIAL PRIMARY KEY,
+    order_uid VARCHAR(255) NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    source VARCHAR(255) NOT NULL,
+    diff JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS order_audit_order_uid_idx ON order_audit (order_uid);
+
 CREATE USER user1 WITH LOGIN PASSWORD '123456789';
 
 GRANT CONNECT ON DATABASE wb_l01 TO user1;
@@ -63,4 +97,10 @@ GRANT USAGE ON SCHEMA public TO user1;
 
 GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO user1;
 
-GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO user1;
\ No newline at end of file
+GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO user1;
+
+-- журнал изменений только дополняется; права выдаются явно, чтобы повторный запуск
+-- на существующей базе настроил их и для таблицы, добавленной позже остальных
+GRANT SELECT, INSERT ON order_audit TO user1;
+GRANT USAGE, SELECT ON SEQUENCE order_audit_id_seq TO user1;
+REVOKE UPDATE, DELETE, TRUNCATE ON order_audit FROM user1;
@@ -6,14 +6,20 @@ import (
 	"L0WB/internal/db"
 	"L0WB/internal/handlers"
 	"L0WB/internal/kafka"
-	"L0WB/internal/models"
+	"L0WB/internal/logger"
+	"L0WB/internal/metrics"
+	"L0WB/internal/tracing"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
-	"log"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"log/slog"
+	"net"
 	"net/http"
-	"time"
+	"os"
+	"sync"
+	"sync/atomic"
 )
 
 type App struct {
@@ -21,12 +27,31 @@ type App struct {
 	Config     *config.AppConfig
 	DB         db.Database
 	HTTPServer *http.Server
-	Consumer   *kafka.Consumer
+	Consumers  []*kafka.Consumer
+	Logger     *slog.Logger
 	Cache      cache.Cache
+	handlers   map[string]func(*kafka.Consumer) kafka.MessageHandler
+	// подключение к БД, которое может появиться после старта (деградированный режим)
+	lazyDB *db.LazyDB
+	// кэш загружен из БД
+	cacheWarmed atomic.Bool
+	// дописывает оставшиеся спаны при остановке
+	shutdownTracing func(context.Context) error
+	// запущенные консьюмеры
+	running sync.WaitGroup
+	// защищает running.Add от гонки с Shutdown: после закрытия консьюмеры
+	// (например, из reconnectDB) больше не запускаются
+	mu     sync.Mutex
+	closed bool
+	// контекст фоновой работы (консьюмеры, переподключение к БД, перечитывание правил),
+	// отменяется в Shutdown
+	ctx  context.Context
+	stop context.CancelFunc
 }
 
 func NewApp() *App {
-	return &App{}
+	ctx, stop := context.WithCancel(context.Background())
+	return &App{ctx: ctx, stop: stop}
 }
 
 func (app *App) Initialize() error {
@@ -35,50 +60,79 @@ func (app *App) Initialize() error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 	app.Config = cfg
-	//fmt.Println(app.Config)
-	log.Println("Waiting for database to start...")
-	time.Sleep(3 * time.Second)
-
-	WbDB, err := new(db.WbDB).NewDB(&config.DBConfig{
-		Host:     app.Config.Postgres.Host,
-		Port:     app.Config.Postgres.Port,
-		User:     app.Config.Postgres.User,
-		Password: app.Config.Postgres.Password,
-		DBName:   app.Config.Postgres.DBName,
-	})
+
+	app.Logger, err = logger.New(app.Config.Log, os.Stdout)
 	if err != nil {
-		return fmt.Errorf("failed to initialize database: %w", err)
+		return fmt.Errorf("failed to create logger: %w", err)
 	}
-	app.DB = WbDB
-	kafkaConsumer, err := kafka.NewConsumer(app.Config.Kafka, app.DB)
+	// пакеты без явного логгера в контексте пишут через slog.Default
+	slog.SetDefault(app.Logger)
+
+	app.shutdownTracing, err = tracing.Init(context.Background(), app.Config.Tracing, "order-service")
 	if err != nil {
-		return fmt.Errorf("failed to create consumer: %w", err)
-	} else {
-		log.Println("Consumer created")
+		return fmt.Errorf("failed to initialize tracing: %w", err)
 	}
-	app.Consumer = kafkaConsumer
 
-	appCache := cache.NewLRUCache(100)
-	orders, err := app.loadOrdersFromDB(context.Background())
-	if err != nil {
-		return fmt.Errorf("failed to load orders from db: %w", err)
+	if rulesFile := app.Config.Validation.RulesFile; rulesFile != "" {
+		rules, err := kafka.LoadRules(rulesFile)
+		if err != nil {
+			return fmt.Errorf("failed to load validation rules: %w", err)
+		}
+		kafka.SetRules(rules)
+		app.Logger.Info("validation rules loaded", "path", rulesFile)
+	}
+	app.lazyDB = db.NewLazyDB()
+	app.DB = app.lazyDB
+	metrics.RegisterDBPool(func() metrics.PoolStats {
+		stats := app.DB.Stats()
+		return metrics.PoolStats{Open: stats.OpenConnections, InUse: stats.InUse, Idle: stats.Idle, WaitCount: stats.WaitCount}
+	})
+	app.Cache = cache.NewLRUCache(100)
+	if err = app.connectDB(app.context(), app.backoff()); err != nil {
+		if !app.Config.Startup.Degraded {
+			return err
+		}
+		app.Logger.Warn("database is unavailable, starting in degraded mode with cache-only reads", "error", err)
+		app.loadCacheSnapshot()
+	}
+
+	app.registerHandlers()
+	if err = app.createConsumers(); err != nil {
+		return err
+	}
+	if err = app.waitForKafka(app.context()); err != nil {
+		if !app.Config.Startup.Degraded {
+			return err
+		}
+		app.Logger.Warn("kafka is unavailable, consumers will keep reconnecting", "error", err)
 	}
-	appCache.LoadAll(orders)
-	app.Cache = appCache
 
 	app.Router = mux.NewRouter()
+	app.Router.Use(handlers.RequestIDMiddleware, handlers.TracingMiddleware, handlers.MetricsMiddleware)
 	app.setRouters()
 	app.HTTPServer = &http.Server{
 		Addr:    app.Config.HTTP.Host + ":" + app.Config.HTTP.Port,
 		Handler: app.Router,
+		BaseContext: func(net.Listener) context.Context {
+			return app.context()
+		},
 	}
 	return nil
 }
 
 func (app *App) Start() error {
-	go app.RunConsumer(context.Background())
+	// консьюмеры без БД только теряли бы сообщения, поэтому в деградированном режиме
+	// они запускаются после подключения
+	if app.dbReady() {
+		app.RunConsumers(app.context())
+	} else {
+		go app.reconnectDB(app.context())
+	}
+	if rulesFile := app.Config.Validation.RulesFile; rulesFile != "" && app.Config.Validation.ReloadInterval > 0 {
+		go kafka.WatchRules(app.context(), rulesFile, app.Config.Validation.ReloadInterval)
+	}
 
-	log.Printf("Starting HTTP server on %s\n", app.HTTPServer.Addr)
+	app.Logger.Info("starting http server", "addr", app.HTTPServer.Addr)
 	if err := app.HTTPServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		return fmt.Errorf("failed to start HTTP server: %w", err)
 	}
@@ -86,30 +140,53 @@ func (app *App) Start() error {
 	return nil
 }
 
-func (app *App) RunConsumer(ctx context.Context) {
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("Shutting down Kafka consumer")
-			return
-		default:
-			msg, err := app.Consumer.ReadMessage(ctx)
-			if err != nil {
-				log.Printf("Failed to read message: %v", err)
-				continue
-			}
-			err = app.Consumer.ProcessMessage(ctx, msg)
-			if err != nil {
-				log.Printf("Failed to process message: %v", err)
-			} else {
-				var order models.Order
-				err = json.Unmarshal(msg.Value, &order)
-				if err == nil {
-					app.Cache.Add(order.OrderUID, &order)
-				}
-			}
+// освобождает ресурсы приложения
+func (app *App) Shutdown(ctx context.Context) error {
+	var errs []error
+	// сначала перестаем принимать запросы и дожидаемся текущих, потом останавливаем
+	// фоновую работу и консьюмеров, закрываем БД и последними выгружаем спаны
+	if app.HTTPServer != nil {
+		if err := app.HTTPServer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to shutdown HTTP server: %w", err))
 		}
 	}
+	app.stop()
+	app.mu.Lock()
+	app.closed = true
+	app.mu.Unlock()
+	if err := app.closeConsumers(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if app.Config != nil && app.Config.Startup.CacheSnapshot != "" && app.Cache != nil {
+		if err := cache.SaveSnapshot(app.Config.Startup.CacheSnapshot, app.Cache); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if app.DB != nil {
+		if err := app.DB.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close database: %w", err))
+		}
+	}
+	if app.shutdownTracing != nil {
+		errs = append(errs, app.shutdownTracing(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// базовый контекст приложения с логгером
+func (app *App) context() context.Context {
+	return logger.WithLogger(app.ctx, app.Logger)
+}
+
+// перечитывает заказ из БД, чтобы кэш не отдавал устаревший статус
+func (app *App) refreshCachedOrder(ctx context.Context, orderUID string) {
+	order, err := app.DB.GetOrder(ctx, orderUID)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to refresh order in cache", logger.KeyOrderUID, orderUID, "error", err)
+		app.Cache.Remove(orderUID)
+		return
+	}
+	app.Cache.Add(orderUID, order)
 }
 
 func (app *App) setRouters() {
@@ -121,12 +198,19 @@ func (app *App) setRouters() {
 
 	handler := handlers.NewProductHandler(app.DB, app.Config, &app.Cache)
 	app.Router.HandleFunc("/order/{order_id}", handler.GetProduct).Methods("GET")
-}
 
-func (app *App) loadOrdersFromDB(ctx context.Context) ([]*models.Order, error) {
-	orders, err := app.DB.GetLastOrders(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get all orders from db: %w", err)
-	}
-	return orders, nil
+	app.Router.Handle("/metrics", promhttp.Handler()).Methods("GET")
+	healthHandler := handlers.NewHealthHandler(app.readinessChecks())
+	app.Router.HandleFunc("/healthz", healthHandler.Live).Methods("GET")
+	app.Router.HandleFunc("/readyz", healthHandler.Ready).Methods("GET")
+
+	api := app.Router.PathPrefix("/api/v1").Subrouter()
+	api.HandleFunc("/orders", handler.CreateOrder).Methods("POST")
+	api.HandleFunc("/orders:batch", handler.CreateOrdersBatch).Methods("POST")
+	api.HandleFunc("/orders:validate", handler.ValidateOrder).Methods("POST")
+	api.HandleFunc("/orders/{order_id}", handler.UpdateOrder).Methods("PUT")
+	api.HandleFunc("/orders/{order_id}", handler.DeleteOrder).Methods("DELETE")
+	api.HandleFunc("/orders/{order_id}/status", handler.UpdateOrderStatus).Methods("PATCH")
+	api.HandleFunc("/orders/{order_id}/history", handler.GetOrderHistory).Methods("GET")
+	api.HandleFunc("/schema/order", handler.GetOrderSchema).Methods("GET")
 }
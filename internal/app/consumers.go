@@ -0,0 +1,106 @@
+package app
+
+import (
+	"L0WB/internal/kafka"
+	"L0WB/internal/logger"
+	"L0WB/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// регистрирует обработчики топиков по именам из config.TopicConfig.Handler
+func (app *App) registerHandlers() {
+	app.handlers = map[string]func(*kafka.Consumer) kafka.MessageHandler{
+		kafka.HandlerOrder:        app.handleOrder,
+		kafka.HandlerStatus:       app.refreshing((*kafka.Consumer).ProcessStatusMessage),
+		kafka.HandlerCancellation: app.refreshing((*kafka.Consumer).ProcessCancellation),
+		kafka.HandlerPayment:      app.refreshing((*kafka.Consumer).ProcessPaymentConfirmation),
+	}
+}
+
+// создает консьюмеров для всех настроенных топиков
+func (app *App) createConsumers() error {
+	for _, topic := range app.Config.AllTopicSettings() {
+		if _, ok := app.handlers[topic.Handler]; !ok {
+			return fmt.Errorf("unknown handler %q for topic %s", topic.Handler, topic.Name)
+		}
+		consumer, err := kafka.NewTopicConsumer(app.Config.Kafka, topic, app.DB)
+		if err != nil {
+			return fmt.Errorf("failed to create consumer for topic %s: %w", topic.Name, err)
+		}
+		app.Logger.Info("consumer created", logger.KeyTopic, topic.Name, "handler", topic.Handler, "concurrency", topic.Concurrency)
+		app.Consumers = append(app.Consumers, consumer)
+	}
+	return nil
+}
+
+// запускает всех консьюмеров; Shutdown дожидается их остановки через app.running,
+// поэтому после начала остановки консьюмеры не запускаются
+func (app *App) RunConsumers(ctx context.Context) {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	if app.closed {
+		return
+	}
+	for _, consumer := range app.Consumers {
+		handle := app.handlers[consumer.Topic().Handler](consumer)
+		app.running.Add(1)
+		go func(consumer *kafka.Consumer) {
+			defer app.running.Done()
+			consumer.Run(ctx, handle)
+		}(consumer)
+	}
+}
+
+// ждет, пока консьюмеры дообработают текущие сообщения, и закрывает читателей
+// и писателя DLQ
+func (app *App) closeConsumers(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		app.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		app.Logger.Warn("consumers did not stop in time", "error", ctx.Err())
+	}
+
+	var errs []error
+	for _, consumer := range app.Consumers {
+		if err := consumer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close consumer for topic %s: %w", consumer.Topic().Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// сохраняет заказ и кладет его в кэш
+func (app *App) handleOrder(c *kafka.Consumer) kafka.MessageHandler {
+	return func(ctx context.Context, msg kafkago.Message) error {
+		order, err := c.ProcessMessage(ctx, msg)
+		if err != nil {
+			return err
+		}
+		_, span := tracing.Start(ctx, "cache.update")
+		app.Cache.Add(order.OrderUID, order)
+		span.End()
+		return nil
+	}
+}
+
+// оборачивает обработку события заказа обновлением заказа в кэше
+func (app *App) refreshing(process func(*kafka.Consumer, context.Context, kafkago.Message) (string, error)) func(*kafka.Consumer) kafka.MessageHandler {
+	return func(c *kafka.Consumer) kafka.MessageHandler {
+		return func(ctx context.Context, msg kafkago.Message) error {
+			orderUID, err := process(c, ctx, msg)
+			if err != nil {
+				return err
+			}
+			app.refreshCachedOrder(ctx, orderUID)
+			return nil
+		}
+	}
+}
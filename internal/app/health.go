@@ -0,0 +1,44 @@
+package app
+
+import (
+	"L0WB/internal/health"
+	"context"
+	"errors"
+	"time"
+)
+
+// таймаут проверки, если в конфиге он не задан
+const defaultCheckTimeout = 2 * time.Second
+
+// проверки готовности: БД, каждый топик Kafka и прогретый кэш
+func (app *App) readinessChecks() []health.Check {
+	cfg := app.Config.Health
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+	checks := []health.Check{{Name: "db", Timeout: orDefault(cfg.DBTimeout, timeout), Func: app.DB.Ping}}
+	for _, consumer := range app.Consumers {
+		checks = append(checks, health.Check{
+			Name:    "kafka:" + consumer.Topic().Name,
+			Timeout: orDefault(cfg.KafkaTimeout, timeout),
+			Func:    consumer.Ping,
+		})
+	}
+	checks = append(checks, health.Check{Name: "cache", Timeout: timeout, Func: app.checkCache})
+	return checks
+}
+
+func (app *App) checkCache(context.Context) error {
+	if !app.cacheWarmed.Load() {
+		return errors.New("cache is not warmed up")
+	}
+	return nil
+}
+
+func orDefault(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
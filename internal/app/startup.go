@@ -0,0 +1,105 @@
+package app
+
+import (
+	"L0WB/internal/cache"
+	"L0WB/internal/db"
+	"L0WB/internal/models"
+	"L0WB/internal/retry"
+	"context"
+	"fmt"
+	"time"
+)
+
+// параметры повторных подключений из конфига
+func (app *App) backoff() retry.Backoff {
+	cfg := app.Config.Startup
+	deadline := cfg.Deadline
+	if deadline <= 0 {
+		deadline = time.Minute
+	}
+	return retry.Backoff{Initial: cfg.InitialBackoff, Max: cfg.MaxBackoff, Deadline: deadline}
+}
+
+// подключается к БД с повторами и прогревает кэш; до успешной загрузки
+// заказов подключение не подставляется в app.DB
+func (app *App) connectDB(ctx context.Context, backoff retry.Backoff) error {
+	var conn db.Database
+	var orders []*models.Order
+	err := retry.Do(ctx, backoff, func(ctx context.Context) error {
+		var err error
+		if conn == nil {
+			if conn, err = db.Open(&app.Config.Storage, &app.Config.Postgres); err != nil {
+				return err
+			}
+		}
+		orders, err = conn.GetLastOrders(ctx)
+		return err
+	}, func(attempt int, err error, wait time.Duration) {
+		app.Logger.Warn("database is not ready, retrying", "attempt", attempt, "retry_in", wait, "error", err)
+	})
+	if err != nil {
+		if conn != nil {
+			conn.Close()
+		}
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	// заказы из снимка могли устареть, поэтому кэш заполняется заново из БД
+	for _, order := range app.Cache.Orders() {
+		app.Cache.Remove(order.OrderUID)
+	}
+	app.Cache.LoadAll(orders)
+	app.cacheWarmed.Store(true)
+	app.lazyDB.Set(conn)
+	app.Logger.Info("database connected", "cached_orders", len(orders))
+	return nil
+}
+
+// в деградированном режиме подключается к БД в фоне без ограничения по времени
+// и после этого запускает консьюмеров
+func (app *App) reconnectDB(ctx context.Context) {
+	backoff := app.backoff()
+	backoff.Deadline = 0
+	if err := app.connectDB(ctx, backoff); err != nil {
+		app.Logger.Error("failed to leave degraded mode", "error", err)
+		return
+	}
+	app.Logger.Info("leaving degraded mode")
+	app.RunConsumers(ctx)
+}
+
+func (app *App) dbReady() bool {
+	return app.lazyDB.Ready()
+}
+
+// ждет, пока брокер станет доступен для всех консьюмеров
+func (app *App) waitForKafka(ctx context.Context) error {
+	err := retry.Do(ctx, app.backoff(), func(ctx context.Context) error {
+		for _, consumer := range app.Consumers {
+			if err := consumer.Ping(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(attempt int, err error, wait time.Duration) {
+		app.Logger.Warn("kafka is not ready, retrying", "attempt", attempt, "retry_in", wait, "error", err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to kafka: %w", err)
+	}
+	return nil
+}
+
+// в деградированном режиме заполняет кэш сохраненным при прошлой остановке снимком,
+// чтобы отдавать заказы до подключения к БД
+func (app *App) loadCacheSnapshot() {
+	path := app.Config.Startup.CacheSnapshot
+	if path == "" {
+		return
+	}
+	n, err := cache.LoadSnapshot(path, app.Cache)
+	if err != nil {
+		app.Logger.Error("failed to load cache snapshot", "path", path, "error", err)
+		return
+	}
+	app.Logger.Info("cache snapshot loaded", "path", path, "cached_orders", n)
+}
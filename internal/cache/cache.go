@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"L0WB/internal/metrics"
 	"L0WB/internal/models"
 	"container/list"
 	"sync"
@@ -11,6 +12,7 @@ type Cache interface {
 	Add(key string, value *models.Order)
 	Remove(key string)
 	LoadAll(orders []*models.Order)
+	Orders() []*models.Order
 }
 
 type LRUCache struct {
@@ -38,8 +40,10 @@ func (c *LRUCache) Get(key string) (*models.Order, bool) {
 	defer c.mu.Unlock()
 	element, ok := c.cache[key]
 	if !ok {
+		metrics.CacheMisses.Inc()
 		return nil, false
 	}
+	metrics.CacheHits.Inc()
 	c.list.MoveToFront(element)
 	return element.Value.(*cacheEntry).value, true
 }
@@ -60,6 +64,7 @@ func (c *LRUCache) Add(key string, value *models.Order) {
 			entry := element.Value.(*cacheEntry)
 			delete(c.cache, entry.key)
 			c.list.Remove(element)
+			metrics.CacheEvictions.Inc()
 		}
 	}
 
@@ -88,6 +93,7 @@ func (c *LRUCache) LoadAll(orders []*models.Order) {
 				entry := element.Value.(*cacheEntry)
 				delete(c.cache, entry.key)
 				c.list.Remove(element)
+				metrics.CacheEvictions.Inc()
 			}
 		}
 		entry := &cacheEntry{key: order.OrderUID, value: order}
@@ -95,3 +101,14 @@ func (c *LRUCache) LoadAll(orders []*models.Order) {
 		c.cache[order.OrderUID] = element
 	}
 }
+
+// заказы от давно не использованных к недавним, чтобы LoadAll восстановил тот же порядок
+func (c *LRUCache) Orders() []*models.Order {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	orders := make([]*models.Order, 0, c.list.Len())
+	for element := c.list.Back(); element != nil; element = element.Prev() {
+		orders = append(orders, element.Value.(*cacheEntry).value)
+	}
+	return orders
+}
@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"L0WB/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// сохраняет заказы из кэша в файл; пишет во временный файл и переименовывает,
+// чтобы при сбое не оставить обрезанный снимок
+func SaveSnapshot(path string, c Cache) error {
+	data, err := json.Marshal(c.Orders())
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache snapshot: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache snapshot: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache snapshot: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save cache snapshot: %w", err)
+	}
+	return nil
+}
+
+// загружает снимок в кэш и возвращает число заказов; отсутствующий файл - не ошибка
+func LoadSnapshot(path string, c Cache) (int, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read cache snapshot: %w", err)
+	}
+	var orders []*models.Order
+	if err = json.Unmarshal(data, &orders); err != nil {
+		return 0, fmt.Errorf("failed to parse cache snapshot: %w", err)
+	}
+	c.LoadAll(orders)
+	return len(orders), nil
+}
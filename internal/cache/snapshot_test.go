@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"L0WB/internal/models"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	c := NewLRUCache(10)
+	for _, uid := range []string{"a", "b", "c"} {
+		c.Add(uid, &models.Order{OrderUID: uid, Status: models.StatusPaid, Version: 2})
+	}
+	// a становится самым недавно использованным
+	c.Get("a")
+	if err := SaveSnapshot(path, c); err != nil {
+		t.Fatalf("SaveSnapshot: %v", err)
+	}
+
+	// при переполнении вытесняется самый давно использованный заказ, как и в исходном кэше
+	restored := NewLRUCache(2)
+	n, err := LoadSnapshot(path, restored)
+	if err != nil || n != 3 {
+		t.Fatalf("LoadSnapshot() = %d, %v; want 3 orders", n, err)
+	}
+	if _, ok := restored.Get("b"); ok {
+		t.Error("least recently used order b was not evicted")
+	}
+	for _, uid := range []string{"a", "c"} {
+		order, ok := restored.Get(uid)
+		if !ok || order.Status != models.StatusPaid || order.Version != 2 {
+			t.Errorf("Get(%q) = %+v, %v", uid, order, ok)
+		}
+	}
+}
+
+func TestLoadSnapshotErrors(t *testing.T) {
+	dir := t.TempDir()
+	if n, err := LoadSnapshot(filepath.Join(dir, "missing.json"), NewLRUCache(1)); n != 0 || err != nil {
+		t.Errorf("LoadSnapshot(missing) = %d, %v; want 0, nil", n, err)
+	}
+	broken := filepath.Join(dir, "broken.json")
+	if err := os.WriteFile(broken, []byte("[{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSnapshot(broken, NewLRUCache(1)); err == nil {
+		t.Error("LoadSnapshot(broken) succeeded")
+	}
+}
@@ -1,22 +1,192 @@
 package config
 
 import (
+	"fmt"
 	"gopkg.in/yaml.v3"
 	"os"
+	"strings"
+	"time"
 )
 
 const CONFIG_FILE = "./config.yaml"
 
 type AppConfig struct {
-	Kafka    KafkaConfig `yaml:"kafka"`
-	Postgres DBConfig    `yaml:"postgres"`
-	HTTP     HTTPConfig  `yaml:"http"`
+	Kafka      KafkaConfig      `yaml:"kafka"`
+	Postgres   DBConfig         `yaml:"postgres"`
+	HTTP       HTTPConfig       `yaml:"http"`
+	Validation ValidationConfig `yaml:"validation"`
+	Log        LogConfig        `yaml:"log"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+	Health     HealthConfig     `yaml:"health"`
+	Startup    StartupConfig    `yaml:"startup"`
+	Storage    StorageConfig    `yaml:"storage"`
+}
+
+type StorageConfig struct {
+	Driver string       `yaml:"driver"` // postgres (database/sql + lib/pq), pgx, sqlite или memory; пусто - postgres
+	SQLite SQLiteConfig `yaml:"sqlite"`
+}
+
+type SQLiteConfig struct {
+	Path        string        `yaml:"path"`         // файл базы; пусто - l0wb.db
+	BusyTimeout time.Duration `yaml:"busy_timeout"` // ожидание блокировки файла другим процессом
+}
+
+// подключение к зависимостям при старте: экспоненциальная задержка между попытками
+// от initial_backoff до max_backoff, но не дольше deadline
+type StartupConfig struct {
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	Deadline       time.Duration `yaml:"deadline"`
+	// если БД недоступна к deadline, запускать HTTP и отдавать заказы только из кэша,
+	// продолжая подключаться в фоне
+	Degraded bool `yaml:"degraded"`
+	// файл, в который кэш сохраняется при остановке и из которого загружается,
+	// если при старте БД недоступна; пусто - не сохранять
+	CacheSnapshot string `yaml:"cache_snapshot"`
+}
+
+// таймауты проверок готовности; нулевые берутся из timeout
+type HealthConfig struct {
+	Timeout      time.Duration `yaml:"timeout"`
+	DBTimeout    time.Duration `yaml:"db_timeout"`
+	KafkaTimeout time.Duration `yaml:"kafka_timeout"`
+}
+
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`     // none, stdout, file или otlp
+	File        string  `yaml:"file"`         // файл для экспортера file
+	Endpoint    string  `yaml:"endpoint"`     // host:port OTLP/HTTP коллектора
+	Insecure    bool    `yaml:"insecure"`     // OTLP без TLS
+	ServiceName string  `yaml:"service_name"` // пусто - order-service (order-producer для producer)
+	SampleRatio float64 `yaml:"sample_ratio"` // доля сэмплируемых трейсов, 0 - все
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level"`  // debug, info, warn или error
+	Format string `yaml:"format"` // json или text
 }
 
 type KafkaConfig struct {
-	BrokerAddress string `yaml:"broker_address"`
-	GroupID       string `yaml:"group_id"`
-	Topic         string `yaml:"topic"`
+	BrokerAddress     string          `yaml:"broker_address"`
+	Brokers           []string        `yaml:"brokers"` // список брокеров; если пуст, используется broker_address
+	TLS               KafkaTLSConfig  `yaml:"tls"`
+	SASL              KafkaSASLConfig `yaml:"sasl"`
+	GroupID           string          `yaml:"group_id"`
+	Topic             string          `yaml:"topic"`
+	StatusTopic       string          `yaml:"status_topic"`
+	SchemaValidation  bool            `yaml:"schema_validation"`   // проверять сырые сообщения по JSON Schema до разбора
+	PayloadFormat     string          `yaml:"payload_format"`      // формат сообщений без заголовка content-type: json, protobuf или avro
+	SchemaRegistryDir string          `yaml:"schema_registry_dir"` // каталог локального реестра схем (registry.yaml, .avsc, .proto)
+	DeadLetterTopic   string          `yaml:"dead_letter_topic"`   // топик для сообщений, которые нельзя обработать (например, неизвестной версии)
+	// топики с обработчиками; если не заданы, используются topic и status_topic
+	Topics []TopicConfig `yaml:"topics"`
+}
+
+type TopicConfig struct {
+	Name             string             `yaml:"name"`
+	Handler          string             `yaml:"handler"`           // order, status, cancellation или payment
+	GroupID          string             `yaml:"group_id"`          // группа консьюмеров топика, по умолчанию kafka.group_id
+	Concurrency      int                `yaml:"concurrency"`       // число читателей топика в группе, по умолчанию 1
+	SchemaValidation *bool              `yaml:"schema_validation"` // переопределяет kafka.schema_validation
+	Consistency      *ConsistencyConfig `yaml:"consistency"`       // переопределяет validation.consistency
+}
+
+type KafkaTLSConfig struct {
+	Enabled            bool   `yaml:"enabled"`
+	CAFile             string `yaml:"ca_file"`   // CA для проверки сертификата брокера; пусто - системные CA
+	CertFile           string `yaml:"cert_file"` // клиентский сертификат для mTLS
+	KeyFile            string `yaml:"key_file"`
+	ServerName         string `yaml:"server_name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// учетные данные задаются явно, файлом или переменной окружения (в этом порядке приоритета: env, file, значение)
+type KafkaSASLConfig struct {
+	Mechanism    string `yaml:"mechanism"` // plain, scram-sha-256 или scram-sha-512; пусто - без SASL
+	Username     string `yaml:"username"`
+	UsernameFile string `yaml:"username_file"`
+	UsernameEnv  string `yaml:"username_env"`
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"password_file"`
+	PasswordEnv  string `yaml:"password_env"`
+}
+
+// список брокеров с учетом старой настройки broker_address
+func (k KafkaConfig) BrokerList() []string {
+	if len(k.Brokers) > 0 {
+		return k.Brokers
+	}
+	return []string{k.BrokerAddress}
+}
+
+// список топиков с учетом старых настроек topic и status_topic
+func (k KafkaConfig) TopicConfigs() []TopicConfig {
+	if len(k.Topics) > 0 {
+		return k.Topics
+	}
+	topics := []TopicConfig{{Name: k.Topic, Handler: "order"}}
+	if k.StatusTopic != "" {
+		topics = append(topics, TopicConfig{Name: k.StatusTopic, Handler: "status"})
+	}
+	return topics
+}
+
+// действующие настройки топика: переопределения из kafka.topics поверх общих
+// kafka.group_id, kafka.schema_validation и validation.consistency
+type TopicSettings struct {
+	Name             string
+	Handler          string
+	GroupID          string
+	Concurrency      int
+	SchemaValidation bool
+	Consistency      ConsistencyConfig
+}
+
+// настройки топика по имени; для ненастроенного топика - общие
+func (c *AppConfig) TopicSettings(name string) TopicSettings {
+	for _, topic := range c.Kafka.TopicConfigs() {
+		if topic.Name == name {
+			return c.topicSettings(topic)
+		}
+	}
+	return c.topicSettings(TopicConfig{Name: name})
+}
+
+// настройки всех топиков с обработчиками
+func (c *AppConfig) AllTopicSettings() []TopicSettings {
+	var topics []TopicSettings
+	for _, topic := range c.Kafka.TopicConfigs() {
+		topics = append(topics, c.topicSettings(topic))
+	}
+	return topics
+}
+
+func (c *AppConfig) topicSettings(topic TopicConfig) TopicSettings {
+	s := TopicSettings{
+		Name:             topic.Name,
+		Handler:          topic.Handler,
+		GroupID:          c.Kafka.GroupID,
+		Concurrency:      topic.Concurrency,
+		SchemaValidation: c.Kafka.SchemaValidation,
+		Consistency:      c.Validation.Consistency,
+	}
+	// по умолчанию все топики читаются одной группой kafka.group_id: смещения хранятся
+	// по топикам раздельно, но появление или уход читателя любого топика
+	// перебалансирует их все; group_id топика выделяет его в свою группу
+	if topic.GroupID != "" {
+		s.GroupID = topic.GroupID
+	}
+	if s.Concurrency < 1 {
+		s.Concurrency = 1
+	}
+	if topic.SchemaValidation != nil {
+		s.SchemaValidation = *topic.SchemaValidation
+	}
+	if topic.Consistency != nil {
+		s.Consistency = *topic.Consistency
+	}
+	return s
 }
 
 type DBConfig struct {
@@ -25,6 +195,21 @@ type DBConfig struct {
 	User     string `yaml:"user"`
 	Password string `yaml:"password"`
 	DBName   string `yaml:"dbname"`
+
+	// пул соединений; нули - значения database/sql по умолчанию
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+
+	SSLMode     string `yaml:"sslmode"`     // disable (по умолчанию), require, verify-ca или verify-full
+	SSLRootCert string `yaml:"sslrootcert"` // CA для verify-ca и verify-full
+	SSLCert     string `yaml:"sslcert"`     // клиентский сертификат
+	SSLKey      string `yaml:"sslkey"`
+
+	ConnectTimeout   time.Duration `yaml:"connect_timeout"`
+	StatementTimeout time.Duration `yaml:"statement_timeout"` // statement_timeout сессии Postgres
+	QueryTimeout     time.Duration `yaml:"query_timeout"`     // ограничение на одну операцию с заказом
 }
 
 type HTTPConfig struct {
@@ -32,6 +217,46 @@ type HTTPConfig struct {
 	Port string `yaml:"port"`
 }
 
+type ValidationConfig struct {
+	// файл с правилами валидации; пусто - встроенные правила
+	RulesFile      string            `yaml:"rules_file"`
+	ReloadInterval time.Duration     `yaml:"reload_interval"`
+	Consistency    ConsistencyConfig `yaml:"consistency"`
+}
+
+type ConsistencyConfig struct {
+	// off, flag (только предупреждать) или reject (отклонять заказ)
+	Mode string `yaml:"mode"`
+	// допустимое расхождение сумм в единицах валюты
+	Tolerance int `yaml:"tolerance"`
+}
+
+// имя пользователя SASL из env, файла или конфига
+func (s KafkaSASLConfig) ResolveUsername() (string, error) {
+	return resolveSecret(s.Username, s.UsernameFile, s.UsernameEnv)
+}
+
+// пароль SASL из env, файла или конфига
+func (s KafkaSASLConfig) ResolvePassword() (string, error) {
+	return resolveSecret(s.Password, s.PasswordFile, s.PasswordEnv)
+}
+
+func resolveSecret(value, file, env string) (string, error) {
+	if env != "" {
+		if v, ok := os.LookupEnv(env); ok {
+			return v, nil
+		}
+	}
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+	return value, nil
+}
+
 func (a *AppConfig) LoadConfig() (*AppConfig, error) {
 	cfg := &AppConfig{}
 	file, err := os.ReadFile(CONFIG_FILE)
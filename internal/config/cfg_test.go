@@ -0,0 +1,59 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTopicSettings(t *testing.T) {
+	off := false
+	reject := ConsistencyConfig{Mode: "reject", Tolerance: 2}
+	cfg := &AppConfig{
+		Kafka: KafkaConfig{
+			GroupID:          "service",
+			SchemaValidation: true,
+			Topics: []TopicConfig{
+				{Name: "orders", Handler: "order", Concurrency: 3},
+				{
+					Name: "legacy-orders", Handler: "order", GroupID: "legacy",
+					SchemaValidation: &off, Consistency: &reject,
+				},
+			},
+		},
+		Validation: ValidationConfig{Consistency: ConsistencyConfig{Mode: "flag", Tolerance: 1}},
+	}
+	tests := []struct {
+		name string
+		want TopicSettings
+	}{
+		{
+			name: "orders",
+			want: TopicSettings{
+				Name: "orders", Handler: "order", GroupID: "service", Concurrency: 3,
+				SchemaValidation: true, Consistency: ConsistencyConfig{Mode: "flag", Tolerance: 1},
+			},
+		},
+		{
+			name: "legacy-orders",
+			want: TopicSettings{
+				Name: "legacy-orders", Handler: "order", GroupID: "legacy", Concurrency: 1,
+				SchemaValidation: false, Consistency: reject,
+			},
+		},
+		{
+			name: "unknown",
+			want: TopicSettings{
+				Name: "unknown", GroupID: "service", Concurrency: 1,
+				SchemaValidation: true, Consistency: ConsistencyConfig{Mode: "flag", Tolerance: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		if got := cfg.TopicSettings(tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("TopicSettings(%q) = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+	if all := cfg.AllTopicSettings(); len(all) != 2 || all[1].GroupID != "legacy" {
+		t.Errorf("AllTopicSettings() = %+v", all)
+	}
+}
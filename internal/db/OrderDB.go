@@ -1,15 +1,43 @@
 package db
 
 import (
+	"L0WB/internal/logger"
 	"L0WB/internal/models"
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
+)
+
+// код ошибки Postgres при нарушении уникальности
+const uniqueViolation = "23505"
+
+const (
+	insertOrderSQL = `
+        INSERT INTO orders (order_uid, track_number, entry, locale, internal_signature, customer_id,
+        delivery_service, shardkey, sm_id, date_created, oof_shard, status)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+    `
+	insertDeliverySQL = `
+        INSERT INTO delivery (order_uid, fio, phone, zip, city, address, region, email)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    `
+	insertPaymentSQL = `
+        INSERT INTO payment (order_uid, transaction_number, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+    `
+	insertItemSQL = `
+        INSERT INTO items (order_uid, chrt_id, track_number, price, rid, item_name, sale, item_size, total_price, nm_id, brand, status)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+    `
 )
 
 // создает новый заказ в базе данных
 func (w *WbDB) CreateOrder(ctx context.Context, order *models.Order) error {
+	if err := initialStatus(order); err != nil {
+		return err
+	}
+	ctx, done := w.startQuery(ctx, "create_order")
+	defer done()
 
 	tx, err := w.BeginTx(ctx, nil)
 	if err != nil {
@@ -18,43 +46,30 @@ func (w *WbDB) CreateOrder(ctx context.Context, order *models.Order) error {
 	defer func() {
 		if err != nil {
 			if rollbackErr := tx.Rollback(); rollbackErr != nil {
-				log.Printf("Failed to rollback transaction: %v", rollbackErr)
+				logger.FromContext(ctx).Error("failed to rollback transaction", "error", rollbackErr)
 			}
 		}
 	}()
 
-	stmtOrder, err := tx.PrepareContext(ctx, `
-        INSERT INTO orders (order_uid, track_number, entry, locale, internal_signature, customer_id,
-        delivery_service, shardkey, sm_id, date_created, oof_shard)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-    `)
+	stmtOrder, err := tx.PrepareContext(ctx, insertOrderSQL)
 	if err != nil {
 		return fmt.Errorf("failed to prepare order statement: %w", err)
 	}
 	defer stmtOrder.Close()
 
-	stmtDelivery, err := tx.PrepareContext(ctx, `
-        INSERT INTO delivery (order_uid, fio, phone, zip, city, address, region, email)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-    `)
+	stmtDelivery, err := tx.PrepareContext(ctx, insertDeliverySQL)
 	if err != nil {
 		return fmt.Errorf("failed to prepare delivery statement: %w", err)
 	}
 	defer stmtDelivery.Close()
 
-	stmtPayment, err := tx.PrepareContext(ctx, `
-        INSERT INTO payment (order_uid, transaction_number, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-    `)
+	stmtPayment, err := tx.PrepareContext(ctx, insertPaymentSQL)
 	if err != nil {
 		return fmt.Errorf("failed to prepare payment statement: %w", err)
 	}
 	defer stmtPayment.Close()
 
-	stmtItem, err := tx.PrepareContext(ctx, `
-        INSERT INTO items (order_uid, chrt_id, track_number, price, rid, item_name, sale, item_size, total_price, nm_id, brand, status)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-    `)
+	stmtItem, err := tx.PrepareContext(ctx, insertItemSQL)
 	if err != nil {
 		return fmt.Errorf("failed to prepare item statement: %w", err)
 	}
@@ -63,8 +78,12 @@ func (w *WbDB) CreateOrder(ctx context.Context, order *models.Order) error {
 	_, err = stmtOrder.ExecContext(ctx,
 		order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
 		order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.DateCreated, order.OofShard,
+		order.Status,
 	)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: %s", ErrOrderExists, order.OrderUID)
+		}
 		return fmt.Errorf("failed to insert order: %w", err)
 	}
 
@@ -95,6 +114,20 @@ func (w *WbDB) CreateOrder(ctx context.Context, order *models.Order) error {
 		}
 	}
 
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO order_status_history (order_uid, to_status, reason)
+        VALUES ($1, $2, $3)
+    `, order.OrderUID, order.Status, "order created")
+	if err != nil {
+		return fmt.Errorf("failed to insert status history: %w", err)
+	}
+
+	order.Version = 1
+	err = writeAudit(ctx, tx, order.OrderUID, models.AuditCreate, nil, order)
+	if err != nil {
+		return err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
@@ -105,21 +138,34 @@ func (w *WbDB) CreateOrder(ctx context.Context, order *models.Order) error {
 
 // получает заказ из базы данных по orderUID
 func (w *WbDB) GetOrder(ctx context.Context, orderUID string) (*models.Order, error) {
+	ctx, done := w.startQuery(ctx, "get_order")
+	defer done()
+	return getOrder(ctx, w.DB, orderUID)
+}
+
+// общие методы *sql.DB и *sql.Tx, нужные для чтения заказа
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func getOrder(ctx context.Context, q querier, orderUID string) (*models.Order, error) {
 	sqlStatement := `
 		SELECT order_uid, track_number, entry, locale, internal_signature, customer_id,
-		delivery_service, shardkey, sm_id, date_created, oof_shard
+		delivery_service, shardkey, sm_id, date_created, oof_shard, status, version
 		FROM orders
-		WHERE order_uid = $1
+		WHERE order_uid = $1 AND deleted_at IS NULL
 	`
-	row := w.QueryRowContext(ctx, sqlStatement, orderUID)
+	row := q.QueryRowContext(ctx, sqlStatement, orderUID)
 	order := &models.Order{}
 	err := row.Scan(
 		&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature,
 		&order.CustomerID, &order.DeliveryService, &order.Shardkey, &order.SmID, &order.DateCreated, &order.OofShard,
+		&order.Status, &order.Version,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("order not found: %s", orderUID)
+			return nil, fmt.Errorf("%w: %s", ErrOrderNotFound, orderUID)
 		}
 		return nil, fmt.Errorf("failed to get order from database: %w", err)
 	}
@@ -129,14 +175,14 @@ func (w *WbDB) GetOrder(ctx context.Context, orderUID string) (*models.Order, er
 		FROM delivery
 		WHERE order_uid = $1
 	`
-	row = w.QueryRowContext(ctx, sqlStatement, orderUID)
+	row = q.QueryRowContext(ctx, sqlStatement, orderUID)
 	err = row.Scan(
 		&order.Delivery.Name, &order.Delivery.Phone, &order.Delivery.Zip,
 		&order.Delivery.City, &order.Delivery.Address, &order.Delivery.Region, &order.Delivery.Email,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			log.Printf("Delivery info not found for order %s", orderUID)
+			logger.FromContext(ctx).Warn("delivery info not found", logger.KeyOrderUID, orderUID)
 		} else {
 			return nil, fmt.Errorf("failed to get delivery info: %w", err)
 		}
@@ -148,7 +194,7 @@ func (w *WbDB) GetOrder(ctx context.Context, orderUID string) (*models.Order, er
 		WHERE order_uid = $1
 	`
 
-	row = w.QueryRowContext(ctx, sqlStatement, orderUID)
+	row = q.QueryRowContext(ctx, sqlStatement, orderUID)
 	err = row.Scan(
 		&order.Payment.TransactionNumber, &order.Payment.RequestID, &order.Payment.Currency,
 		&order.Payment.Provider, &order.Payment.Amount, &order.Payment.PaymentDT, &order.Payment.Bank,
@@ -156,7 +202,7 @@ func (w *WbDB) GetOrder(ctx context.Context, orderUID string) (*models.Order, er
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			log.Printf("Payment info not found for order %s", orderUID)
+			logger.FromContext(ctx).Warn("payment info not found", logger.KeyOrderUID, orderUID)
 		} else {
 			return nil, fmt.Errorf("failed to get payment info: %w", err)
 		}
@@ -167,7 +213,7 @@ func (w *WbDB) GetOrder(ctx context.Context, orderUID string) (*models.Order, er
 		FROM items
 		WHERE order_uid = $1
 	`
-	rows, err := w.QueryContext(ctx, sqlStatement, orderUID)
+	rows, err := q.QueryContext(ctx, sqlStatement, orderUID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get items: %w", err)
 	}
@@ -189,12 +235,17 @@ func (w *WbDB) GetOrder(ctx context.Context, orderUID string) (*models.Order, er
 	return order, nil
 }
 
-// получает последние 100 заказов
+// получает 100 последних по date_created неудаленных заказов, от новых к старым;
+// при равной дате - по order_uid
 func (w *WbDB) GetLastOrders(ctx context.Context) ([]*models.Order, error) {
+	ctx, done := w.startQuery(ctx, "get_last_orders")
+	defer done()
 	sqlStatement := `
         SELECT order_uid, track_number, entry, locale, internal_signature, customer_id,
-        delivery_service, shardkey, sm_id, date_created, oof_shard
+        delivery_service, shardkey, sm_id, date_created, oof_shard, status, version
         FROM orders
+        WHERE deleted_at IS NULL
+        ORDER BY ` + w.dateCreatedOrder() + ` DESC, order_uid
         LIMIT 100
     `
 	rows, err := w.QueryContext(ctx, sqlStatement)
@@ -209,6 +260,7 @@ func (w *WbDB) GetLastOrders(ctx context.Context) ([]*models.Order, error) {
 		err := rows.Scan(
 			&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature,
 			&order.CustomerID, &order.DeliveryService, &order.Shardkey, &order.SmID, &order.DateCreated, &order.OofShard,
+			&order.Status, &order.Version,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan order: %w", err)
@@ -291,3 +343,224 @@ func (w *WbDB) populateRelatedData(ctx context.Context, order *models.Order) err
 
 	return nil
 }
+
+// переводит заказ в новый статус и записывает переход в историю;
+// version = 0 отключает проверку версии
+func (w *WbDB) UpdateOrderStatus(ctx context.Context, orderUID string, status models.OrderStatus, reason string, version int) (err error) {
+	ctx, done := w.startQuery(ctx, "update_order_status")
+	defer done()
+	if !status.Valid() {
+		return fmt.Errorf("%w: %s", ErrInvalidStatus, status)
+	}
+
+	tx, err := w.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				logger.FromContext(ctx).Error("failed to rollback transaction", "error", rollbackErr)
+			}
+		}
+	}()
+
+	var current models.OrderStatus
+	var currentVersion int
+	err = tx.QueryRowContext(ctx,
+		`SELECT status, version FROM orders WHERE order_uid = $1 AND deleted_at IS NULL`+w.forUpdate(), orderUID,
+	).Scan(&current, &currentVersion)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: %s", ErrOrderNotFound, orderUID)
+		}
+		return fmt.Errorf("failed to get order status: %w", err)
+	}
+	if version != 0 && version != currentVersion {
+		return fmt.Errorf("%w: %s has version %d", ErrVersionConflict, orderUID, currentVersion)
+	}
+	if current == status {
+		return tx.Commit()
+	}
+	if !current.CanTransitionTo(status) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, current, status)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE orders SET status = $2, version = version + 1 WHERE order_uid = $1`, orderUID, status)
+	if err != nil {
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO order_status_history (order_uid, from_status, to_status, reason)
+        VALUES ($1, $2, $3, $4)
+    `, orderUID, current, status, reason)
+	if err != nil {
+		return fmt.Errorf("failed to insert status history: %w", err)
+	}
+	err = writeAudit(ctx, tx, orderUID, models.AuditStatus,
+		map[string]any{"status": current, "version": currentVersion},
+		map[string]any{"status": status, "version": currentVersion + 1, "reason": reason},
+	)
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// полностью заменяет заказ вместе с доставкой, оплатой и товарами;
+// order.Version должна совпадать с текущей версией заказа в базе
+func (w *WbDB) UpdateOrder(ctx context.Context, order *models.Order) (err error) {
+	ctx, done := w.startQuery(ctx, "update_order")
+	defer done()
+	tx, err := w.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				logger.FromContext(ctx).Error("failed to rollback transaction", "error", rollbackErr)
+			}
+		}
+	}()
+
+	before, err := w.lockOrder(ctx, tx, order.OrderUID)
+	if err != nil {
+		return err
+	}
+
+	var version int
+	var status models.OrderStatus
+	err = tx.QueryRowContext(ctx, `
+        UPDATE orders SET track_number = $3, entry = $4, locale = $5, internal_signature = $6,
+        customer_id = $7, delivery_service = $8, shardkey = $9, sm_id = $10, date_created = $11,
+        oof_shard = $12, version = version + 1
+        WHERE order_uid = $1 AND version = $2 AND deleted_at IS NULL
+        RETURNING version, status
+    `, order.OrderUID, order.Version, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
+		order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.DateCreated, order.OofShard,
+	).Scan(&version, &status)
+	if err == sql.ErrNoRows {
+		return versionMismatch(ctx, tx, order.OrderUID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update order: %w", err)
+	}
+
+	for _, table := range []string{"delivery", "payment", "items"} {
+		_, err = tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE order_uid = $1`, order.OrderUID)
+		if err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, insertDeliverySQL,
+		order.OrderUID, order.Delivery.Name, order.Delivery.Phone, order.Delivery.Zip,
+		order.Delivery.City, order.Delivery.Address, order.Delivery.Region, order.Delivery.Email,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert delivery: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, insertPaymentSQL,
+		order.OrderUID, order.Payment.TransactionNumber, order.Payment.RequestID, order.Payment.Currency,
+		order.Payment.Provider, order.Payment.Amount, order.Payment.PaymentDT, order.Payment.Bank,
+		order.Payment.DeliveryCost, order.Payment.GoodsTotal, order.Payment.CustomFee,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert payment: %w", err)
+	}
+
+	stmtItem, err := tx.PrepareContext(ctx, insertItemSQL)
+	if err != nil {
+		return fmt.Errorf("failed to prepare item statement: %w", err)
+	}
+	defer stmtItem.Close()
+	for _, item := range order.Items {
+		_, err = stmtItem.ExecContext(ctx,
+			order.OrderUID, item.ChrtID, item.TrackNumber, item.Price, item.RID, item.ItemName,
+			item.Sale, item.ItemSize, item.TotalPrice, item.NmID, item.Brand, item.Status,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert item: %w", err)
+		}
+	}
+
+	order.Version = version
+	order.Status = status
+	err = writeAudit(ctx, tx, order.OrderUID, models.AuditUpdate, before, order)
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// помечает заказ удаленным; version = 0 отключает проверку версии
+func (w *WbDB) DeleteOrder(ctx context.Context, orderUID string, version int) (err error) {
+	ctx, done := w.startQuery(ctx, "delete_order")
+	defer done()
+	tx, err := w.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				logger.FromContext(ctx).Error("failed to rollback transaction", "error", rollbackErr)
+			}
+		}
+	}()
+
+	before, err := w.lockOrder(ctx, tx, orderUID)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `
+        UPDATE orders SET deleted_at = CURRENT_TIMESTAMP, version = version + 1
+        WHERE order_uid = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2)
+    `, orderUID, version)
+	if err != nil {
+		return fmt.Errorf("failed to delete order: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete order: %w", err)
+	}
+	if affected == 0 {
+		return versionMismatch(ctx, tx, orderUID)
+	}
+	err = writeAudit(ctx, tx, orderUID, models.AuditDelete, before, nil)
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// выясняет, почему условное изменение заказа не затронуло ни одной строки:
+// заказа нет или его версия уже изменилась
+func versionMismatch(ctx context.Context, tx *sql.Tx, orderUID string) error {
+	var current int
+	err := tx.QueryRowContext(ctx,
+		`SELECT version FROM orders WHERE order_uid = $1 AND deleted_at IS NULL`, orderUID,
+	).Scan(&current)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: %s", ErrOrderNotFound, orderUID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get order version: %w", err)
+	}
+	return fmt.Errorf("%w: %s has version %d", ErrVersionConflict, orderUID, current)
+}
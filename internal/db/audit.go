@@ -0,0 +1,178 @@
+package db
+
+import (
+	"L0WB/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// кто и откуда меняет заказ
+type AuditInfo struct {
+	Actor  string
+	Source string
+}
+
+type auditKey struct{}
+
+// кладет в контекст сведения об инициаторе изменения для журнала
+func WithAudit(ctx context.Context, info AuditInfo) context.Context {
+	return context.WithValue(ctx, auditKey{}, info)
+}
+
+func AuditFromContext(ctx context.Context) AuditInfo {
+	info, _ := ctx.Value(auditKey{}).(AuditInfo)
+	if info.Actor == "" {
+		info.Actor = "system"
+	}
+	if info.Source == "" {
+		info.Source = "unknown"
+	}
+	return info
+}
+
+// изменение одного поля: значения до и после
+type fieldChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// записывает изменение заказа в журнал в рамках транзакции tx
+func writeAudit(ctx context.Context, tx *sql.Tx, orderUID string, action models.AuditAction, before, after any) error {
+	diff, err := auditDiff(before, after)
+	if err != nil {
+		return fmt.Errorf("failed to build audit diff: %w", err)
+	}
+	info := AuditFromContext(ctx)
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO order_audit (order_uid, action, actor, source, diff)
+        VALUES ($1, $2, $3, $4, $5)
+    `, orderUID, action, info.Actor, info.Source, string(diff))
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+	return nil
+}
+
+// строит JSON вида {"delivery.phone": {"before": ..., "after": ...}} только по изменившимся полям
+func auditDiff(before, after any) ([]byte, error) {
+	b, err := toJSONValue(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := toJSONValue(after)
+	if err != nil {
+		return nil, err
+	}
+	changes := map[string]fieldChange{}
+	diffValues("", b, a, changes)
+	return json.Marshal(changes)
+}
+
+func toJSONValue(v any) (any, error) {
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil() {
+		return nil, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	err = json.Unmarshal(raw, &out)
+	return out, err
+}
+
+func diffValues(path string, before, after any, changes map[string]fieldChange) {
+	bm, bIsMap := before.(map[string]any)
+	am, aIsMap := after.(map[string]any)
+	if (bIsMap || before == nil) && (aIsMap || after == nil) && (bIsMap || aIsMap) {
+		keys := map[string]struct{}{}
+		for k := range bm {
+			keys[k] = struct{}{}
+		}
+		for k := range am {
+			keys[k] = struct{}{}
+		}
+		for k := range keys {
+			diffValues(joinPath(path, k), bm[k], am[k], changes)
+		}
+		return
+	}
+
+	bs, bIsSlice := before.([]any)
+	as, aIsSlice := after.([]any)
+	if (bIsSlice || before == nil) && (aIsSlice || after == nil) && (bIsSlice || aIsSlice) {
+		for i := 0; i < len(bs) || i < len(as); i++ {
+			var bv, av any
+			if i < len(bs) {
+				bv = bs[i]
+			}
+			if i < len(as) {
+				av = as[i]
+			}
+			diffValues(path+"["+strconv.Itoa(i)+"]", bv, av, changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		changes[path] = fieldChange{Before: before, After: after}
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// блокирует строку заказа до конца транзакции и возвращает его текущее состояние
+func (w *WbDB) lockOrder(ctx context.Context, tx *sql.Tx, orderUID string) (*models.Order, error) {
+	var uid string
+	err := tx.QueryRowContext(ctx,
+		`SELECT order_uid FROM orders WHERE order_uid = $1 AND deleted_at IS NULL`+w.forUpdate(), orderUID,
+	).Scan(&uid)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrOrderNotFound, orderUID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock order: %w", err)
+	}
+	return getOrder(ctx, tx, orderUID)
+}
+
+// получает журнал изменений заказа, включая удаленные заказы
+func (w *WbDB) GetOrderHistory(ctx context.Context, orderUID string) ([]*models.AuditEntry, error) {
+	ctx, done := w.startQuery(ctx, "get_order_history")
+	defer done()
+	rows, err := w.QueryContext(ctx, `
+        SELECT id, order_uid, action, actor, source, diff, created_at
+        FROM order_audit
+        WHERE order_uid = $1
+        ORDER BY id
+    `, orderUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order history: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*models.AuditEntry{}
+	for rows.Next() {
+		entry := &models.AuditEntry{}
+		var diff []byte
+		err = rows.Scan(&entry.ID, &entry.OrderUID, &entry.Action, &entry.Actor, &entry.Source, &diff, &entry.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entry.Diff = diff
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate audit entries: %w", err)
+	}
+	return entries, nil
+}
@@ -2,33 +2,246 @@ package db
 
 import (
 	"L0WB/internal/config"
+	"L0WB/internal/metrics"
 	"L0WB/internal/models"
+	"L0WB/internal/tracing"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	_ "github.com/lib/pq"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"math"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"strings"
+	"time"
+)
+
+var (
+	ErrOrderExists   = errors.New("order already exists")
+	ErrOrderNotFound = errors.New("order not found")
+
+	ErrVersionConflict = errors.New("order version conflict")
+
+	ErrInvalidStatus     = errors.New("invalid order status")
+	ErrInvalidTransition = errors.New("invalid status transition")
+)
+
+// статус нового заказа: пустой заменяется на created, любой другой отклоняется,
+// чтобы заказ нельзя было создать в обход переходов между статусами
+func initialStatus(order *models.Order) error {
+	switch order.Status {
+	case "":
+		order.Status = models.StatusCreated
+	case models.StatusCreated:
+	default:
+		return fmt.Errorf("%w: new order must be %s, got %s", ErrInvalidStatus, models.StatusCreated, order.Status)
+	}
+	return nil
+}
+
+// реализации Database, выбираются через storage.driver
+const (
+	DriverPostgres = "postgres"
+	DriverPgx      = "pgx"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
 )
 
 type Database interface {
-	NewDB(*config.DBConfig) (Database, error)
+	// проверяет, что хранилище доступно
+	Ping(ctx context.Context) error
+	// состояние пула соединений
+	Stats() Stats
+	// закрывает соединения; после Close хранилище не используется
+	Close() error
 	CreateOrder(ctx context.Context, order *models.Order) error
 	GetOrder(ctx context.Context, orderUID string) (*models.Order, error)
 	GetLastOrders(ctx context.Context) ([]*models.Order, error)
+	UpdateOrderStatus(ctx context.Context, orderUID string, status models.OrderStatus, reason string, version int) error
+	UpdateOrder(ctx context.Context, order *models.Order) error
+	DeleteOrder(ctx context.Context, orderUID string, version int) error
+	GetOrderHistory(ctx context.Context, orderUID string) ([]*models.AuditEntry, error)
+}
+
+// состояние пула соединений; у хранилищ без пула все поля нулевые
+type Stats struct {
+	MaxOpenConnections int           `json:"max_open_connections"` // 0 - без ограничения
+	OpenConnections    int           `json:"open_connections"`
+	InUse              int           `json:"in_use"`
+	Idle               int           `json:"idle"`
+	WaitCount          int64         `json:"wait_count"`    // сколько раз ждали свободное соединение
+	WaitDuration       time.Duration `json:"wait_duration"` // суммарное время ожидания
+}
+
+// открывает хранилище драйвером из storage.driver; секция postgres нужна
+// драйверам postgres и pgx
+func Open(storage *config.StorageConfig, postgres *config.DBConfig) (Database, error) {
+	switch storage.Driver {
+	case "", DriverPostgres:
+		return NewPostgresDB(postgres)
+	case DriverPgx:
+		return NewPgxDB(postgres)
+	case DriverSQLite:
+		return NewSQLiteDB(&storage.SQLite)
+	case DriverMemory:
+		return NewMemoryDB(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", storage.Driver)
+	}
 }
 
 type WbDB struct {
 	*sql.DB
+	// ограничение на один вызов метода; 0 - только контекст вызывающего
+	queryTimeout time.Duration
+	// то же хранилище поверх SQLite, см. NewSQLiteDB
+	sqlite bool
 }
 
-func (w *WbDB) NewDB(cfg *config.DBConfig) (Database, error) {
-	source := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName)
-	dbConn, err := sql.Open("postgres", source)
+// подключается к Postgres через database/sql и lib/pq
+func NewPostgresDB(cfg *config.DBConfig) (*WbDB, error) {
+	dbConn, err := sql.Open("postgres", dsn(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to database: %v", err)
 	}
-	if err := dbConn.Ping(); err != nil {
+	dbConn.SetMaxOpenConns(cfg.MaxOpenConns)
+	if cfg.MaxIdleConns > 0 {
+		dbConn.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	dbConn.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	dbConn.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	ctx := context.Background()
+	if cfg.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.ConnectTimeout)
+		defer cancel()
+	}
+	if err := dbConn.PingContext(ctx); err != nil {
+		dbConn.Close()
 		return nil, fmt.Errorf("error pinging db: %w", err)
 	}
-	return &WbDB{dbConn}, err
+	return &WbDB{DB: dbConn, queryTimeout: cfg.QueryTimeout}, nil
+}
+
+func (w *WbDB) Ping(ctx context.Context) error {
+	return w.DB.PingContext(ctx)
+}
+
+func (w *WbDB) Stats() Stats {
+	s := w.DB.Stats()
+	return Stats{
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDuration:       s.WaitDuration,
+	}
+}
+
+// строка подключения lib/pq; statement_timeout передается серверу как параметр сессии
+func dsn(cfg *config.DBConfig) string {
+	sslMode := cfg.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	params := []string{
+		"host=" + quoteDSN(cfg.Host),
+		"port=" + quoteDSN(cfg.Port),
+		"user=" + quoteDSN(cfg.User),
+		"password=" + quoteDSN(cfg.Password),
+		"dbname=" + quoteDSN(cfg.DBName),
+		"sslmode=" + quoteDSN(sslMode),
+	}
+	if cfg.SSLRootCert != "" {
+		params = append(params, "sslrootcert="+quoteDSN(cfg.SSLRootCert))
+	}
+	if cfg.SSLCert != "" {
+		params = append(params, "sslcert="+quoteDSN(cfg.SSLCert))
+	}
+	if cfg.SSLKey != "" {
+		params = append(params, "sslkey="+quoteDSN(cfg.SSLKey))
+	}
+	if cfg.ConnectTimeout > 0 {
+		params = append(params, fmt.Sprintf("connect_timeout=%d", int(math.Ceil(cfg.ConnectTimeout.Seconds()))))
+	}
+	if cfg.StatementTimeout > 0 {
+		params = append(params, fmt.Sprintf("statement_timeout=%d", cfg.StatementTimeout.Milliseconds()))
+	}
+	return strings.Join(params, " ")
+}
+
+// экранирует значение для строки подключения вида key='value'
+func quoteDSN(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
+
+// открывает дочерний спан операции хранилища, ограничивает ее queryTimeout и по завершении
+// пишет длительность в метрики; вызывать как ctx, done := w.startQuery(ctx, "get_order"); defer done()
+func (w *WbDB) startQuery(ctx context.Context, operation string) (context.Context, func()) {
+	system := "postgresql"
+	if w.sqlite {
+		system = "sqlite"
+	}
+	return startQuery(ctx, system, operation, w.queryTimeout)
+}
+
+// блокировка строки до конца транзакции; в SQLite ее нет, но пишущие транзакции
+// там и так идут по одной
+func (w *WbDB) forUpdate() string {
+	if w.sqlite {
+		return ""
+	}
+	return " FOR UPDATE"
+}
+
+// выражение для сортировки по date_created: SQLite хранит время строкой со смещением
+// часового пояса, поэтому сравнивать нужно julianday, а не текст
+func (w *WbDB) dateCreatedOrder() string {
+	if w.sqlite {
+		return "julianday(date_created)"
+	}
+	return "date_created"
+}
+
+// нарушение уникальности (повторный order_uid) в любом из драйверов
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	var pgErr *pgconn.PgError
+	var sqliteErr *sqlite.Error
+	switch {
+	case errors.As(err, &pqErr):
+		return pqErr.Code == uniqueViolation
+	case errors.As(err, &pgErr):
+		return pgErr.Code == uniqueViolation
+	case errors.As(err, &sqliteErr):
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+	}
+	return false
+}
+
+func startQuery(ctx context.Context, system, operation string, timeout time.Duration) (context.Context, func()) {
+	start := time.Now()
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	ctx, span := tracing.Start(ctx, "db."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", system),
+			attribute.String("db.operation.name", operation),
+		))
+	return ctx, func() {
+		metrics.ObserveQuery(operation, start)
+		span.End()
+		cancel()
+	}
 }
@@ -0,0 +1,411 @@
+// Package dbtest - общий набор проверок поведения реализаций db.Database.
+// Реализация подключается из своего теста:
+//
+//	func TestMemoryDB(t *testing.T) {
+//		dbtest.Run(t, func(t *testing.T) db.Database { return db.NewMemoryDB() })
+//	}
+package dbtest
+
+import (
+	"L0WB/internal/db"
+	"L0WB/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// возвращает пустое хранилище для одного подтеста; закрыть его (Close)
+// и очистить таблицы фабрика может через t.Cleanup
+type Factory func(t *testing.T) db.Database
+
+// прогоняет все проверки, каждую на новом хранилище из factory
+func Run(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store db.Database)
+	}{
+		{"Ping", testPing},
+		{"CreateAndGet", testCreateAndGet},
+		{"CreateInitialStatus", testCreateInitialStatus},
+		{"CreateDuplicate", testCreateDuplicate},
+		{"GetMissing", testGetMissing},
+		{"GetLastOrders", testGetLastOrders},
+		{"GetLastOrdersOrder", testGetLastOrdersOrder},
+		{"GetLastOrdersLimit", testGetLastOrdersLimit},
+		{"ReturnedOrderIsCopy", testReturnedOrderIsCopy},
+		{"UpdateOrderStatus", testUpdateOrderStatus},
+		{"UpdateOrder", testUpdateOrder},
+		{"DeleteOrder", testDeleteOrder},
+		{"OrderHistory", testOrderHistory},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, factory(t))
+		})
+	}
+}
+
+// тестовый заказ с двумя товарами
+func NewOrder(uid string) *models.Order {
+	return &models.Order{
+		OrderUID:          uid,
+		TrackNumber:       "WBILMTESTTRACK",
+		Entry:             "WBIL",
+		Locale:            "en",
+		InternalSignature: "",
+		CustomerID:        "test",
+		DeliveryService:   "meest",
+		Shardkey:          "9",
+		SmID:              99,
+		DateCreated:       time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC),
+		OofShard:          "1",
+		Delivery: models.Delivery{
+			Name:    "Test Testov",
+			Phone:   "+9720000000",
+			Zip:     "2639809",
+			City:    "Kiryat Mozkin",
+			Address: "Ploshad Mira 15",
+			Region:  "Kraiot",
+			Email:   "test@gmail.com",
+		},
+		Payment: models.Payment{
+			TransactionNumber: uid,
+			Currency:          "USD",
+			Provider:          "wbpay",
+			Amount:            1817,
+			PaymentDT:         1637907727,
+			Bank:              "alpha",
+			DeliveryCost:      1500,
+			GoodsTotal:        317,
+		},
+		Items: []models.Item{
+			{ChrtID: 9934930, TrackNumber: "WBILMTESTTRACK", Price: 453, RID: "ab4219087a764ae0btest", ItemName: "Mascaras", Sale: 30, ItemSize: "0", TotalPrice: 317, NmID: 2389212, Brand: "Vivienne Sabo", Status: 202},
+			{ChrtID: 9934931, TrackNumber: "WBILMTESTTRACK", Price: 100, RID: "ab4219087a764ae0btest2", ItemName: "Brush", Sale: 0, ItemSize: "0", TotalPrice: 100, NmID: 2389213, Brand: "Vivienne Sabo", Status: 202},
+		},
+	}
+}
+
+func mustCreate(t *testing.T, store db.Database, uid string) *models.Order {
+	t.Helper()
+	order := NewOrder(uid)
+	if err := store.CreateOrder(context.Background(), order); err != nil {
+		t.Fatalf("CreateOrder(%s): %v", uid, err)
+	}
+	return order
+}
+
+func mustGet(t *testing.T, store db.Database, uid string) *models.Order {
+	t.Helper()
+	order, err := store.GetOrder(context.Background(), uid)
+	if err != nil {
+		t.Fatalf("GetOrder(%s): %v", uid, err)
+	}
+	return order
+}
+
+// сравнивает заказы, не учитывая часовой пояс даты создания
+func assertOrder(t *testing.T, got, want *models.Order) {
+	t.Helper()
+	if !got.DateCreated.Equal(want.DateCreated) {
+		t.Errorf("date_created = %v, want %v", got.DateCreated, want.DateCreated)
+	}
+	g, w := *got, *want
+	g.DateCreated, w.DateCreated = time.Time{}, time.Time{}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("order mismatch:\n got  %+v\n want %+v", g, w)
+	}
+}
+
+func assertError(t *testing.T, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Fatalf("error = %v, want %v", err, target)
+	}
+}
+
+func testPing(t *testing.T, store db.Database) {
+	if err := store.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if stats := store.Stats(); stats.InUse < 0 || stats.Idle < 0 || stats.InUse+stats.Idle > stats.OpenConnections {
+		t.Errorf("inconsistent pool stats: %+v", stats)
+	}
+}
+
+func testCreateAndGet(t *testing.T, store db.Database) {
+	order := mustCreate(t, store, "create-and-get")
+	if order.Status != models.StatusCreated || order.Version != 1 {
+		t.Errorf("created order has status %q version %d, want created 1", order.Status, order.Version)
+	}
+	assertOrder(t, mustGet(t, store, order.OrderUID), order)
+
+	noItems := NewOrder("create-no-items")
+	noItems.Status = models.StatusCreated
+	noItems.Items = nil
+	if err := store.CreateOrder(context.Background(), noItems); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	assertOrder(t, mustGet(t, store, noItems.OrderUID), noItems)
+}
+
+// новый заказ создается только в статусе created: остальные статусы достижимы
+// лишь через UpdateOrderStatus
+func testCreateInitialStatus(t *testing.T, store db.Database) {
+	ctx := context.Background()
+	for _, status := range []models.OrderStatus{models.StatusPaid, models.StatusDelivered, models.StatusReturned, "unknown"} {
+		order := NewOrder("create-" + string(status))
+		order.Status = status
+		assertError(t, store.CreateOrder(ctx, order), db.ErrInvalidStatus)
+		_, err := store.GetOrder(ctx, order.OrderUID)
+		assertError(t, err, db.ErrOrderNotFound)
+	}
+}
+
+func testCreateDuplicate(t *testing.T, store db.Database) {
+	ctx := context.Background()
+	mustCreate(t, store, "duplicate")
+	assertError(t, store.CreateOrder(ctx, NewOrder("duplicate")), db.ErrOrderExists)
+
+	// order_uid удаленного заказа тоже занят
+	if err := store.DeleteOrder(ctx, "duplicate", 0); err != nil {
+		t.Fatalf("DeleteOrder: %v", err)
+	}
+	assertError(t, store.CreateOrder(ctx, NewOrder("duplicate")), db.ErrOrderExists)
+}
+
+func testGetMissing(t *testing.T, store db.Database) {
+	_, err := store.GetOrder(context.Background(), "missing")
+	assertError(t, err, db.ErrOrderNotFound)
+}
+
+func testGetLastOrders(t *testing.T, store db.Database) {
+	ctx := context.Background()
+	orders, err := store.GetLastOrders(ctx)
+	if err != nil {
+		t.Fatalf("GetLastOrders: %v", err)
+	}
+	if orders == nil || len(orders) != 0 {
+		t.Fatalf("GetLastOrders on empty store = %v, want empty slice", orders)
+	}
+
+	var want []string
+	for i := 0; i < 5; i++ {
+		uid := fmt.Sprintf("last-%d", i)
+		mustCreate(t, store, uid)
+		want = append(want, uid)
+	}
+	if err = store.DeleteOrder(ctx, "last-2", 0); err != nil {
+		t.Fatalf("DeleteOrder: %v", err)
+	}
+	want = append(want[:2], want[3:]...)
+
+	orders, err = store.GetLastOrders(ctx)
+	if err != nil {
+		t.Fatalf("GetLastOrders: %v", err)
+	}
+	var got []string
+	for _, order := range orders {
+		got = append(got, order.OrderUID)
+		if len(order.Items) != 2 {
+			t.Errorf("order %s has %d items, want 2", order.OrderUID, len(order.Items))
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetLastOrders = %v, want %v", got, want)
+	}
+}
+
+func lastOrderUIDs(t *testing.T, store db.Database) []string {
+	t.Helper()
+	orders, err := store.GetLastOrders(context.Background())
+	if err != nil {
+		t.Fatalf("GetLastOrders: %v", err)
+	}
+	uids := make([]string, 0, len(orders))
+	for _, order := range orders {
+		uids = append(uids, order.OrderUID)
+	}
+	return uids
+}
+
+// от новых к старым по date_created независимо от часового пояса, при равной дате - по order_uid
+func testGetLastOrdersOrder(t *testing.T, store db.Database) {
+	dates := []struct {
+		uid  string
+		date time.Time
+	}{
+		{"o-1", time.Date(2021, 1, 2, 10, 0, 0, 0, time.UTC)},
+		{"o-3", time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)},
+		{"o-2", time.Date(2021, 1, 3, 10, 0, 0, 0, time.UTC)},
+		// 09:00 UTC: раньше o-0 и o-1, хотя в местном времени позже
+		{"o-4", time.Date(2021, 1, 2, 12, 0, 0, 0, time.FixedZone("MSK", 3*60*60))},
+		{"o-0", time.Date(2021, 1, 2, 10, 0, 0, 0, time.UTC)},
+	}
+	for _, d := range dates {
+		order := NewOrder(d.uid)
+		order.DateCreated = d.date
+		if err := store.CreateOrder(context.Background(), order); err != nil {
+			t.Fatalf("CreateOrder(%s): %v", d.uid, err)
+		}
+	}
+	want := []string{"o-2", "o-0", "o-1", "o-4", "o-3"}
+	if got := lastOrderUIDs(t, store); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetLastOrders = %v, want %v", got, want)
+	}
+}
+
+// возвращает не больше 100 заказов, самые старые отбрасываются
+func testGetLastOrdersLimit(t *testing.T, store db.Database) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 101; i++ {
+		order := NewOrder(fmt.Sprintf("limit-%03d", i))
+		order.DateCreated = start.Add(time.Duration(i) * time.Minute)
+		order.Items = nil
+		if err := store.CreateOrder(context.Background(), order); err != nil {
+			t.Fatalf("CreateOrder: %v", err)
+		}
+	}
+	got := lastOrderUIDs(t, store)
+	if len(got) != 100 {
+		t.Fatalf("GetLastOrders returned %d orders, want 100", len(got))
+	}
+	if got[0] != "limit-100" || got[99] != "limit-001" {
+		t.Errorf("GetLastOrders returned %s..%s, want limit-100..limit-001", got[0], got[99])
+	}
+}
+
+func testReturnedOrderIsCopy(t *testing.T, store db.Database) {
+	order := mustCreate(t, store, "copy")
+	order.TrackNumber = "changed"
+	got := mustGet(t, store, "copy")
+	got.Items[0].Price = 1
+	got.Delivery.City = "changed"
+
+	got = mustGet(t, store, "copy")
+	if got.TrackNumber == "changed" || got.Items[0].Price == 1 || got.Delivery.City == "changed" {
+		t.Errorf("stored order changed through returned value: %+v", got)
+	}
+}
+
+func testUpdateOrderStatus(t *testing.T, store db.Database) {
+	ctx := context.Background()
+	mustCreate(t, store, "status")
+
+	assertError(t, store.UpdateOrderStatus(ctx, "status", "unknown", "", 0), db.ErrInvalidStatus)
+	assertError(t, store.UpdateOrderStatus(ctx, "status", models.StatusShipped, "", 0), db.ErrInvalidTransition)
+	assertError(t, store.UpdateOrderStatus(ctx, "status", models.StatusPaid, "", 5), db.ErrVersionConflict)
+	assertError(t, store.UpdateOrderStatus(ctx, "missing", models.StatusPaid, "", 0), db.ErrOrderNotFound)
+
+	if err := store.UpdateOrderStatus(ctx, "status", models.StatusPaid, "paid", 1); err != nil {
+		t.Fatalf("UpdateOrderStatus: %v", err)
+	}
+	order := mustGet(t, store, "status")
+	if order.Status != models.StatusPaid || order.Version != 2 {
+		t.Errorf("order has status %q version %d, want paid 2", order.Status, order.Version)
+	}
+
+	// повтор текущего статуса ничего не меняет
+	if err := store.UpdateOrderStatus(ctx, "status", models.StatusPaid, "again", 0); err != nil {
+		t.Fatalf("UpdateOrderStatus with same status: %v", err)
+	}
+	if order = mustGet(t, store, "status"); order.Version != 2 {
+		t.Errorf("version after same status = %d, want 2", order.Version)
+	}
+}
+
+func testUpdateOrder(t *testing.T, store db.Database) {
+	ctx := context.Background()
+	mustCreate(t, store, "update")
+	if err := store.UpdateOrderStatus(ctx, "update", models.StatusPaid, "", 0); err != nil {
+		t.Fatalf("UpdateOrderStatus: %v", err)
+	}
+
+	order := mustGet(t, store, "update")
+	order.TrackNumber = "NEWTRACK"
+	order.Delivery.City = "Moscow"
+	order.Items = order.Items[:1]
+	order.Status = models.StatusDelivered
+	if err := store.UpdateOrder(ctx, order); err != nil {
+		t.Fatalf("UpdateOrder: %v", err)
+	}
+	// статус меняется только через UpdateOrderStatus
+	if order.Version != 3 || order.Status != models.StatusPaid {
+		t.Errorf("updated order has status %q version %d, want paid 3", order.Status, order.Version)
+	}
+	assertOrder(t, mustGet(t, store, "update"), order)
+
+	stale := NewOrder("update")
+	stale.Version = 2
+	assertError(t, store.UpdateOrder(ctx, stale), db.ErrVersionConflict)
+
+	missing := NewOrder("missing")
+	missing.Version = 1
+	assertError(t, store.UpdateOrder(ctx, missing), db.ErrOrderNotFound)
+}
+
+func testDeleteOrder(t *testing.T, store db.Database) {
+	ctx := context.Background()
+	mustCreate(t, store, "delete")
+
+	assertError(t, store.DeleteOrder(ctx, "delete", 2), db.ErrVersionConflict)
+	if err := store.DeleteOrder(ctx, "delete", 1); err != nil {
+		t.Fatalf("DeleteOrder: %v", err)
+	}
+	_, err := store.GetOrder(ctx, "delete")
+	assertError(t, err, db.ErrOrderNotFound)
+	assertError(t, store.DeleteOrder(ctx, "delete", 0), db.ErrOrderNotFound)
+	assertError(t, store.UpdateOrderStatus(ctx, "delete", models.StatusPaid, "", 0), db.ErrOrderNotFound)
+}
+
+func testOrderHistory(t *testing.T, store db.Database) {
+	ctx := db.WithAudit(context.Background(), db.AuditInfo{Actor: "tester", Source: "dbtest"})
+	order := NewOrder("history")
+	if err := store.CreateOrder(ctx, order); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if err := store.UpdateOrderStatus(ctx, "history", models.StatusPaid, "paid", 0); err != nil {
+		t.Fatalf("UpdateOrderStatus: %v", err)
+	}
+	order.Version = 2
+	order.Delivery.City = "Moscow"
+	if err := store.UpdateOrder(ctx, order); err != nil {
+		t.Fatalf("UpdateOrder: %v", err)
+	}
+	if err := store.DeleteOrder(ctx, "history", 0); err != nil {
+		t.Fatalf("DeleteOrder: %v", err)
+	}
+
+	entries, err := store.GetOrderHistory(ctx, "history")
+	if err != nil {
+		t.Fatalf("GetOrderHistory: %v", err)
+	}
+	want := []models.AuditAction{models.AuditCreate, models.AuditStatus, models.AuditUpdate, models.AuditDelete}
+	if len(entries) != len(want) {
+		t.Fatalf("GetOrderHistory returned %d entries, want %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		if entry.Action != want[i] || entry.OrderUID != "history" || entry.Actor != "tester" || entry.Source != "dbtest" {
+			t.Errorf("entry %d = %+v, want action %s by tester from dbtest", i, entry, want[i])
+		}
+		if i > 0 && entry.ID <= entries[i-1].ID {
+			t.Errorf("entry %d id %d is not greater than previous %d", i, entry.ID, entries[i-1].ID)
+		}
+	}
+
+	var diff map[string]struct{ Before, After any }
+	if err = json.Unmarshal(entries[2].Diff, &diff); err != nil {
+		t.Fatalf("update diff is not JSON: %v", err)
+	}
+	if len(diff) != 2 || diff["delivery.city"].After != "Moscow" || diff["version"].After != float64(3) {
+		t.Errorf("update diff = %s, want delivery.city and version", entries[2].Diff)
+	}
+
+	entries, err = store.GetOrderHistory(ctx, "missing")
+	if err != nil || entries == nil || len(entries) != 0 {
+		t.Errorf("GetOrderHistory(missing) = %v, %v, want empty slice", entries, err)
+	}
+}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// конструкторы по готовой строке подключения, только для тестов
+
+func NewPostgresDBFromDSN(dsn string) (*WbDB, error) {
+	conn, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err = conn.Ping(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &WbDB{DB: conn}, nil
+}
+
+func NewPgxDBFromDSN(dsn string) (*PgxDB, error) {
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err = pool.Ping(context.Background()); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return &PgxDB{pool: pool}, nil
+}
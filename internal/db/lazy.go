@@ -0,0 +1,119 @@
+package db
+
+import (
+	"L0WB/internal/models"
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+var ErrUnavailable = errors.New("database is unavailable")
+
+// обертка над Database, подключение к которой может появиться позже:
+// пока оно не установлено, все методы возвращают ErrUnavailable
+type LazyDB struct {
+	db atomic.Pointer[Database]
+}
+
+func NewLazyDB() *LazyDB {
+	return &LazyDB{}
+}
+
+// подставляет установленное подключение
+func (l *LazyDB) Set(db Database) {
+	l.db.Store(&db)
+}
+
+// подключение установлено
+func (l *LazyDB) Ready() bool {
+	return l.db.Load() != nil
+}
+
+func (l *LazyDB) get() (Database, error) {
+	db := l.db.Load()
+	if db == nil {
+		return nil, ErrUnavailable
+	}
+	return *db, nil
+}
+
+func (l *LazyDB) Ping(ctx context.Context) error {
+	db, err := l.get()
+	if err != nil {
+		return err
+	}
+	return db.Ping(ctx)
+}
+
+func (l *LazyDB) Stats() Stats {
+	db, err := l.get()
+	if err != nil {
+		return Stats{}
+	}
+	return db.Stats()
+}
+
+// закрывает подставленное подключение, если оно есть
+func (l *LazyDB) Close() error {
+	db, err := l.get()
+	if err != nil {
+		return nil
+	}
+	return db.Close()
+}
+
+func (l *LazyDB) CreateOrder(ctx context.Context, order *models.Order) error {
+	db, err := l.get()
+	if err != nil {
+		return err
+	}
+	return db.CreateOrder(ctx, order)
+}
+
+func (l *LazyDB) GetOrder(ctx context.Context, orderUID string) (*models.Order, error) {
+	db, err := l.get()
+	if err != nil {
+		return nil, err
+	}
+	return db.GetOrder(ctx, orderUID)
+}
+
+func (l *LazyDB) GetLastOrders(ctx context.Context) ([]*models.Order, error) {
+	db, err := l.get()
+	if err != nil {
+		return nil, err
+	}
+	return db.GetLastOrders(ctx)
+}
+
+func (l *LazyDB) UpdateOrderStatus(ctx context.Context, orderUID string, status models.OrderStatus, reason string, version int) error {
+	db, err := l.get()
+	if err != nil {
+		return err
+	}
+	return db.UpdateOrderStatus(ctx, orderUID, status, reason, version)
+}
+
+func (l *LazyDB) UpdateOrder(ctx context.Context, order *models.Order) error {
+	db, err := l.get()
+	if err != nil {
+		return err
+	}
+	return db.UpdateOrder(ctx, order)
+}
+
+func (l *LazyDB) DeleteOrder(ctx context.Context, orderUID string, version int) error {
+	db, err := l.get()
+	if err != nil {
+		return err
+	}
+	return db.DeleteOrder(ctx, orderUID, version)
+}
+
+func (l *LazyDB) GetOrderHistory(ctx context.Context, orderUID string) ([]*models.AuditEntry, error) {
+	db, err := l.get()
+	if err != nil {
+		return nil, err
+	}
+	return db.GetOrderHistory(ctx, orderUID)
+}
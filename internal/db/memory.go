@@ -0,0 +1,246 @@
+package db
+
+import (
+	"L0WB/internal/models"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// хранилище заказов в памяти с той же семантикой, что у WbDB: ошибки дубликата,
+// отсутствия заказа и конфликта версий, мягкое удаление, порядок GetLastOrders.
+// Для тестов и демонстрации без Postgres
+type MemoryDB struct {
+	mu sync.RWMutex
+	// заказы, включая удаленные: их order_uid остается занят, как первичный ключ в orders
+	orders  map[string]*memoryOrder
+	audit   []*models.AuditEntry
+	auditID int64
+}
+
+type memoryOrder struct {
+	order   *models.Order
+	deleted bool
+}
+
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{orders: map[string]*memoryOrder{}}
+}
+
+func (m *MemoryDB) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// соединений нет
+func (m *MemoryDB) Stats() Stats {
+	return Stats{}
+}
+
+func (m *MemoryDB) Close() error {
+	return nil
+}
+
+// копия заказа, чтобы вызывающий не мог изменить хранимые данные
+func copyOrder(order *models.Order) *models.Order {
+	c := *order
+	c.Items = nil
+	if len(order.Items) > 0 {
+		c.Items = append([]models.Item(nil), order.Items...)
+	}
+	return &c
+}
+
+// неудаленный заказ; вызывается под блокировкой
+func (m *MemoryDB) get(orderUID string) (*models.Order, error) {
+	stored, ok := m.orders[orderUID]
+	if !ok || stored.deleted {
+		return nil, fmt.Errorf("%w: %s", ErrOrderNotFound, orderUID)
+	}
+	return stored.order, nil
+}
+
+// записывает изменение в журнал; вызывается под блокировкой
+func (m *MemoryDB) writeAudit(ctx context.Context, orderUID string, action models.AuditAction, before, after any) error {
+	diff, err := auditDiff(before, after)
+	if err != nil {
+		return fmt.Errorf("failed to build audit diff: %w", err)
+	}
+	info := AuditFromContext(ctx)
+	m.auditID++
+	m.audit = append(m.audit, &models.AuditEntry{
+		ID:        m.auditID,
+		OrderUID:  orderUID,
+		Action:    action,
+		Actor:     info.Actor,
+		Source:    info.Source,
+		Diff:      diff,
+		CreatedAt: time.Now(),
+	})
+	return nil
+}
+
+// создает новый заказ
+func (m *MemoryDB) CreateOrder(ctx context.Context, order *models.Order) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := initialStatus(order); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.orders[order.OrderUID]; ok {
+		return fmt.Errorf("%w: %s", ErrOrderExists, order.OrderUID)
+	}
+
+	order.Version = 1
+	if err := m.writeAudit(ctx, order.OrderUID, models.AuditCreate, nil, order); err != nil {
+		return err
+	}
+	m.orders[order.OrderUID] = &memoryOrder{order: copyOrder(order)}
+	return nil
+}
+
+// получает заказ по orderUID
+func (m *MemoryDB) GetOrder(ctx context.Context, orderUID string) (*models.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	order, err := m.get(orderUID)
+	if err != nil {
+		return nil, err
+	}
+	return copyOrder(order), nil
+}
+
+// получает 100 последних по date_created неудаленных заказов, от новых к старым;
+// при равной дате - по order_uid, как ORDER BY date_created DESC, order_uid в WbDB
+func (m *MemoryDB) GetLastOrders(ctx context.Context) ([]*models.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	orders := []*models.Order{}
+	for _, stored := range m.orders {
+		if !stored.deleted {
+			orders = append(orders, copyOrder(stored.order))
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		a, b := orders[i], orders[j]
+		if !a.DateCreated.Equal(b.DateCreated) {
+			return a.DateCreated.After(b.DateCreated)
+		}
+		return a.OrderUID < b.OrderUID
+	})
+	if len(orders) > 100 {
+		orders = orders[:100]
+	}
+	return orders, nil
+}
+
+// переводит заказ в новый статус; version = 0 отключает проверку версии
+func (m *MemoryDB) UpdateOrderStatus(ctx context.Context, orderUID string, status models.OrderStatus, reason string, version int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !status.Valid() {
+		return fmt.Errorf("%w: %s", ErrInvalidStatus, status)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	order, err := m.get(orderUID)
+	if err != nil {
+		return err
+	}
+	if version != 0 && version != order.Version {
+		return fmt.Errorf("%w: %s has version %d", ErrVersionConflict, orderUID, order.Version)
+	}
+	if order.Status == status {
+		return nil
+	}
+	if !order.Status.CanTransitionTo(status) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, order.Status, status)
+	}
+
+	err = m.writeAudit(ctx, orderUID, models.AuditStatus,
+		map[string]any{"status": order.Status, "version": order.Version},
+		map[string]any{"status": status, "version": order.Version + 1, "reason": reason},
+	)
+	if err != nil {
+		return err
+	}
+	order.Status = status
+	order.Version++
+	return nil
+}
+
+// полностью заменяет заказ; order.Version должна совпадать с текущей версией
+func (m *MemoryDB) UpdateOrder(ctx context.Context, order *models.Order) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	before, err := m.get(order.OrderUID)
+	if err != nil {
+		return err
+	}
+	if order.Version != before.Version {
+		return fmt.Errorf("%w: %s has version %d", ErrVersionConflict, order.OrderUID, before.Version)
+	}
+
+	order.Version = before.Version + 1
+	order.Status = before.Status
+	if err = m.writeAudit(ctx, order.OrderUID, models.AuditUpdate, before, order); err != nil {
+		return err
+	}
+	m.orders[order.OrderUID].order = copyOrder(order)
+	return nil
+}
+
+// помечает заказ удаленным; version = 0 отключает проверку версии
+func (m *MemoryDB) DeleteOrder(ctx context.Context, orderUID string, version int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	before, err := m.get(orderUID)
+	if err != nil {
+		return err
+	}
+	if version != 0 && version != before.Version {
+		return fmt.Errorf("%w: %s has version %d", ErrVersionConflict, orderUID, before.Version)
+	}
+
+	if err = m.writeAudit(ctx, orderUID, models.AuditDelete, before, nil); err != nil {
+		return err
+	}
+	before.Version++
+	m.orders[orderUID].deleted = true
+	return nil
+}
+
+// получает журнал изменений заказа, включая удаленные заказы
+func (m *MemoryDB) GetOrderHistory(ctx context.Context, orderUID string) ([]*models.AuditEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entries := []*models.AuditEntry{}
+	for _, entry := range m.audit {
+		if entry.OrderUID == orderUID {
+			c := *entry
+			c.Diff = append([]byte(nil), entry.Diff...)
+			entries = append(entries, &c)
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,13 @@
+package db_test
+
+import (
+	"L0WB/internal/db"
+	"L0WB/internal/db/dbtest"
+	"testing"
+)
+
+func TestMemoryDB(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T) db.Database {
+		return db.NewMemoryDB()
+	})
+}
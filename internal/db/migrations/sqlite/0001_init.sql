@@ -0,0 +1,83 @@
+-- схема повторяет init.sql для Postgres
+CREATE TABLE orders (
+    order_uid VARCHAR(255) PRIMARY KEY,
+    track_number VARCHAR(255),
+    entry VARCHAR(255),
+    locale VARCHAR(10),
+    internal_signature VARCHAR(255),
+    customer_id VARCHAR(255),
+    delivery_service VARCHAR(255),
+    shardkey VARCHAR(10),
+    sm_id INT,
+    date_created DATETIME,
+    oof_shard VARCHAR(10),
+    status VARCHAR(20) NOT NULL DEFAULT 'created',
+    version INT NOT NULL DEFAULT 1,
+    deleted_at DATETIME
+);
+
+CREATE TABLE delivery (
+    order_uid VARCHAR(255) PRIMARY KEY REFERENCES orders(order_uid) ON DELETE CASCADE,
+    fio VARCHAR(255),
+    phone VARCHAR(40),
+    zip VARCHAR(40),
+    city VARCHAR(255),
+    address VARCHAR(255),
+    region VARCHAR(255),
+    email VARCHAR(255)
+);
+
+CREATE TABLE payment (
+    order_uid VARCHAR(255) PRIMARY KEY REFERENCES orders(order_uid) ON DELETE CASCADE,
+    transaction_number VARCHAR(255),
+    request_id VARCHAR(255),
+    currency VARCHAR(10),
+    provider VARCHAR(50),
+    amount INT,
+    payment_dt BIGINT,
+    bank VARCHAR(255),
+    delivery_cost INT,
+    goods_total INT,
+    custom_fee INT
+);
+
+CREATE TABLE items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_uid VARCHAR(255) REFERENCES orders(order_uid) ON DELETE CASCADE,
+    chrt_id INT,
+    track_number VARCHAR(255),
+    price INT,
+    rid VARCHAR(255),
+    item_name VARCHAR(255),
+    sale INT,
+    item_size VARCHAR(10),
+    total_price INT,
+    nm_id INT,
+    brand VARCHAR(255),
+    status INT
+);
+
+CREATE INDEX items_order_uid_idx ON items (order_uid);
+
+CREATE TABLE order_status_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_uid VARCHAR(255) REFERENCES orders(order_uid) ON DELETE CASCADE,
+    from_status VARCHAR(20),
+    to_status VARCHAR(20) NOT NULL,
+    reason TEXT,
+    changed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX order_status_history_order_uid_idx ON order_status_history (order_uid);
+
+CREATE TABLE order_audit (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_uid VARCHAR(255) NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    source VARCHAR(255) NOT NULL,
+    diff TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX order_audit_order_uid_idx ON order_audit (order_uid);
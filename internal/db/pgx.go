@@ -0,0 +1,563 @@
+package db
+
+import (
+	"L0WB/internal/config"
+	"L0WB/internal/logger"
+	"L0WB/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+// реализация Database на pgx/pgxpool: запросы кэшируются как подготовленные выражения
+// на каждом соединении пула, связанные данные заказа пишутся и читаются одним пакетом
+// (pipeline), большие списки товаров вставляются через COPY
+type PgxDB struct {
+	pool *pgxpool.Pool
+	// ограничение на один вызов метода; 0 - только контекст вызывающего
+	queryTimeout time.Duration
+}
+
+const (
+	// подготовленных выражений на одно соединение
+	statementCacheCapacity = 256
+	// с этого числа товаров они вставляются через COPY, а не пакетом INSERT
+	copyItemsThreshold = 20
+)
+
+var itemColumns = []string{
+	"order_uid", "chrt_id", "track_number", "price", "rid", "item_name",
+	"sale", "item_size", "total_price", "nm_id", "brand", "status",
+}
+
+const (
+	selectOrdersSQL = `
+        SELECT order_uid, track_number, entry, locale, internal_signature, customer_id,
+        delivery_service, shardkey, sm_id, date_created, oof_shard, status, version
+        FROM orders
+    `
+	selectDeliverySQL = `SELECT fio, phone, zip, city, address, region, email FROM delivery WHERE order_uid = $1`
+	selectPaymentSQL  = `
+        SELECT transaction_number, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee
+        FROM payment WHERE order_uid = $1
+    `
+	selectItemsSQL = `
+        SELECT chrt_id, track_number, price, rid, item_name, sale, item_size, total_price, nm_id, brand, status
+        FROM items WHERE order_uid = $1 ORDER BY id
+    `
+	insertStatusHistorySQL = `
+        INSERT INTO order_status_history (order_uid, from_status, to_status, reason)
+        VALUES ($1, $2, $3, $4)
+    `
+	insertAuditSQL = `
+        INSERT INTO order_audit (order_uid, action, actor, source, diff)
+        VALUES ($1, $2, $3, $4, $5)
+    `
+)
+
+// общее у пула и транзакции
+type pgxQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// создает пул pgx и проверяет подключение
+func NewPgxDB(cfg *config.DBConfig) (*PgxDB, error) {
+	poolCfg, err := pgxpool.ParseConfig(dsn(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing database config: %w", err)
+	}
+	poolCfg.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeCacheStatement
+	poolCfg.ConnConfig.StatementCacheCapacity = statementCacheCapacity
+	if cfg.MaxOpenConns > 0 {
+		poolCfg.MaxConns = int32(cfg.MaxOpenConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		poolCfg.MaxConnLifetime = cfg.ConnMaxLifetime
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = cfg.ConnMaxIdleTime
+	}
+
+	ctx := context.Background()
+	if cfg.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.ConnectTimeout)
+		defer cancel()
+	}
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to database: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("error pinging db: %w", err)
+	}
+	return &PgxDB{pool: pool, queryTimeout: cfg.QueryTimeout}, nil
+}
+
+func (p *PgxDB) Ping(ctx context.Context) error {
+	return p.pool.Ping(ctx)
+}
+
+func (p *PgxDB) Stats() Stats {
+	s := p.pool.Stat()
+	return Stats{
+		MaxOpenConnections: int(s.MaxConns()),
+		OpenConnections:    int(s.TotalConns()),
+		InUse:              int(s.AcquiredConns()),
+		Idle:               int(s.IdleConns()),
+		WaitCount:          s.EmptyAcquireCount(),
+		// pgxpool считает время всех Acquire; без ожидания оно близко к нулю
+		WaitDuration: s.AcquireDuration(),
+	}
+}
+
+// pgxpool.Pool.Close ждет, пока вернутся все занятые соединения
+func (p *PgxDB) Close() error {
+	p.pool.Close()
+	return nil
+}
+
+func (p *PgxDB) startQuery(ctx context.Context, operation string) (context.Context, func()) {
+	return startQuery(ctx, "postgresql", operation, p.queryTimeout)
+}
+
+// откатывает транзакцию, если метод завершился ошибкой
+func rollbackOnError(ctx context.Context, tx pgx.Tx, err *error) {
+	if *err == nil {
+		return
+	}
+	if rollbackErr := tx.Rollback(ctx); rollbackErr != nil && !errors.Is(rollbackErr, pgx.ErrTxClosed) {
+		logger.FromContext(ctx).Error("failed to rollback transaction", "error", rollbackErr)
+	}
+}
+
+// выполняет все запросы пакета за один обмен с сервером
+func execBatch(ctx context.Context, q pgxQuerier, batch *pgx.Batch) error {
+	results := q.SendBatch(ctx, batch)
+	for i := 0; i < batch.Len(); i++ {
+		if _, err := results.Exec(); err != nil {
+			results.Close()
+			return err
+		}
+	}
+	return results.Close()
+}
+
+// ставит в пакет запись доставки, оплаты и (если их немного) товаров
+func queueOrderDetails(batch *pgx.Batch, order *models.Order) {
+	batch.Queue(insertDeliverySQL,
+		order.OrderUID, order.Delivery.Name, order.Delivery.Phone, order.Delivery.Zip,
+		order.Delivery.City, order.Delivery.Address, order.Delivery.Region, order.Delivery.Email,
+	)
+	batch.Queue(insertPaymentSQL,
+		order.OrderUID, order.Payment.TransactionNumber, order.Payment.RequestID, order.Payment.Currency,
+		order.Payment.Provider, order.Payment.Amount, order.Payment.PaymentDT, order.Payment.Bank,
+		order.Payment.DeliveryCost, order.Payment.GoodsTotal, order.Payment.CustomFee,
+	)
+	if len(order.Items) >= copyItemsThreshold {
+		return
+	}
+	for _, item := range order.Items {
+		batch.Queue(insertItemSQL,
+			order.OrderUID, item.ChrtID, item.TrackNumber, item.Price, item.RID, item.ItemName,
+			item.Sale, item.ItemSize, item.TotalPrice, item.NmID, item.Brand, item.Status,
+		)
+	}
+}
+
+// вставляет товары через COPY, если они не попали в пакет
+func copyItems(ctx context.Context, tx pgx.Tx, order *models.Order) error {
+	if len(order.Items) < copyItemsThreshold {
+		return nil
+	}
+	_, err := tx.CopyFrom(ctx, pgx.Identifier{"items"}, itemColumns,
+		pgx.CopyFromSlice(len(order.Items), func(i int) ([]any, error) {
+			item := order.Items[i]
+			return []any{
+				order.OrderUID, item.ChrtID, item.TrackNumber, item.Price, item.RID, item.ItemName,
+				item.Sale, item.ItemSize, item.TotalPrice, item.NmID, item.Brand, item.Status,
+			}, nil
+		}))
+	if err != nil {
+		return fmt.Errorf("failed to copy items: %w", err)
+	}
+	return nil
+}
+
+func writeAuditPgx(ctx context.Context, tx pgx.Tx, orderUID string, action models.AuditAction, before, after any) error {
+	diff, err := auditDiff(before, after)
+	if err != nil {
+		return fmt.Errorf("failed to build audit diff: %w", err)
+	}
+	info := AuditFromContext(ctx)
+	_, err = tx.Exec(ctx, insertAuditSQL, orderUID, action, info.Actor, info.Source, json.RawMessage(diff))
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+	return nil
+}
+
+// создает новый заказ в базе данных
+func (p *PgxDB) CreateOrder(ctx context.Context, order *models.Order) (err error) {
+	if err = initialStatus(order); err != nil {
+		return err
+	}
+	ctx, done := p.startQuery(ctx, "create_order")
+	defer done()
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(ctx, tx, &err)
+
+	batch := &pgx.Batch{}
+	batch.Queue(insertOrderSQL,
+		order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
+		order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.DateCreated, order.OofShard,
+		order.Status,
+	)
+	queueOrderDetails(batch, order)
+	batch.Queue(insertStatusHistorySQL, order.OrderUID, nil, order.Status, "order created")
+	if err = execBatch(ctx, tx, batch); err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: %s", ErrOrderExists, order.OrderUID)
+		}
+		return fmt.Errorf("failed to insert order: %w", err)
+	}
+	if err = copyItems(ctx, tx, order); err != nil {
+		return err
+	}
+
+	order.Version = 1
+	if err = writeAuditPgx(ctx, tx, order.OrderUID, models.AuditCreate, nil, order); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// читает заказы по запросу к orders, а их доставку, оплату и товары - одним пакетом
+func loadOrders(ctx context.Context, q pgxQuerier, query string, args ...any) ([]*models.Order, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orders from database: %w", err)
+	}
+	orders := []*models.Order{}
+	for rows.Next() {
+		order := &models.Order{}
+		err = rows.Scan(
+			&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature,
+			&order.CustomerID, &order.DeliveryService, &order.Shardkey, &order.SmID, &order.DateCreated, &order.OofShard,
+			&order.Status, &order.Version,
+		)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		orders = append(orders, order)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	if len(orders) == 0 {
+		return orders, nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, order := range orders {
+		batch.Queue(selectDeliverySQL, order.OrderUID)
+		batch.Queue(selectPaymentSQL, order.OrderUID)
+		batch.Queue(selectItemsSQL, order.OrderUID)
+	}
+	results := q.SendBatch(ctx, batch)
+	defer results.Close()
+	for _, order := range orders {
+		if err = scanOrderDetails(ctx, results, order); err != nil {
+			return nil, fmt.Errorf("failed to populate related data for order %s: %w", order.OrderUID, err)
+		}
+	}
+	return orders, nil
+}
+
+func scanOrderDetails(ctx context.Context, results pgx.BatchResults, order *models.Order) error {
+	err := results.QueryRow().Scan(
+		&order.Delivery.Name, &order.Delivery.Phone, &order.Delivery.Zip,
+		&order.Delivery.City, &order.Delivery.Address, &order.Delivery.Region, &order.Delivery.Email,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		logger.FromContext(ctx).Warn("delivery info not found", logger.KeyOrderUID, order.OrderUID)
+	} else if err != nil {
+		return fmt.Errorf("failed to get delivery info: %w", err)
+	}
+
+	err = results.QueryRow().Scan(
+		&order.Payment.TransactionNumber, &order.Payment.RequestID, &order.Payment.Currency,
+		&order.Payment.Provider, &order.Payment.Amount, &order.Payment.PaymentDT, &order.Payment.Bank,
+		&order.Payment.DeliveryCost, &order.Payment.GoodsTotal, &order.Payment.CustomFee,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		logger.FromContext(ctx).Warn("payment info not found", logger.KeyOrderUID, order.OrderUID)
+	} else if err != nil {
+		return fmt.Errorf("failed to get payment info: %w", err)
+	}
+
+	rows, err := results.Query()
+	if err != nil {
+		return fmt.Errorf("failed to get items: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		item := models.Item{}
+		err = rows.Scan(
+			&item.ChrtID, &item.TrackNumber, &item.Price, &item.RID, &item.ItemName,
+			&item.Sale, &item.ItemSize, &item.TotalPrice, &item.NmID, &item.Brand, &item.Status,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan item: %w", err)
+		}
+		order.Items = append(order.Items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate items: %w", err)
+	}
+	return nil
+}
+
+// получает заказ по ID
+func (p *PgxDB) GetOrder(ctx context.Context, orderUID string) (*models.Order, error) {
+	ctx, done := p.startQuery(ctx, "get_order")
+	defer done()
+	orders, err := loadOrders(ctx, p.pool, selectOrdersSQL+` WHERE order_uid = $1 AND deleted_at IS NULL`, orderUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrOrderNotFound, orderUID)
+	}
+	return orders[0], nil
+}
+
+// получает 100 последних по date_created неудаленных заказов, от новых к старым;
+// при равной дате - по order_uid
+func (p *PgxDB) GetLastOrders(ctx context.Context) ([]*models.Order, error) {
+	ctx, done := p.startQuery(ctx, "get_last_orders")
+	defer done()
+	return loadOrders(ctx, p.pool, selectOrdersSQL+` WHERE deleted_at IS NULL ORDER BY date_created DESC, order_uid LIMIT 100`)
+}
+
+// блокирует строку заказа до конца транзакции и возвращает его текущее состояние
+func lockOrderPgx(ctx context.Context, tx pgx.Tx, orderUID string) (*models.Order, error) {
+	orders, err := loadOrders(ctx, tx, selectOrdersSQL+` WHERE order_uid = $1 AND deleted_at IS NULL FOR UPDATE`, orderUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock order: %w", err)
+	}
+	if len(orders) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrOrderNotFound, orderUID)
+	}
+	return orders[0], nil
+}
+
+// переводит заказ в новый статус и записывает переход в историю;
+// version = 0 отключает проверку версии
+func (p *PgxDB) UpdateOrderStatus(ctx context.Context, orderUID string, status models.OrderStatus, reason string, version int) (err error) {
+	ctx, done := p.startQuery(ctx, "update_order_status")
+	defer done()
+	if !status.Valid() {
+		return fmt.Errorf("%w: %s", ErrInvalidStatus, status)
+	}
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(ctx, tx, &err)
+
+	var current models.OrderStatus
+	var currentVersion int
+	err = tx.QueryRow(ctx,
+		`SELECT status, version FROM orders WHERE order_uid = $1 AND deleted_at IS NULL FOR UPDATE`, orderUID,
+	).Scan(&current, &currentVersion)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %s", ErrOrderNotFound, orderUID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get order status: %w", err)
+	}
+	if version != 0 && version != currentVersion {
+		return fmt.Errorf("%w: %s has version %d", ErrVersionConflict, orderUID, currentVersion)
+	}
+	if current == status {
+		return tx.Commit(ctx)
+	}
+	if !current.CanTransitionTo(status) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, current, status)
+	}
+
+	batch := &pgx.Batch{}
+	batch.Queue(`UPDATE orders SET status = $2, version = version + 1 WHERE order_uid = $1`, orderUID, status)
+	batch.Queue(insertStatusHistorySQL, orderUID, current, status, reason)
+	if err = execBatch(ctx, tx, batch); err != nil {
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+	err = writeAuditPgx(ctx, tx, orderUID, models.AuditStatus,
+		map[string]any{"status": current, "version": currentVersion},
+		map[string]any{"status": status, "version": currentVersion + 1, "reason": reason},
+	)
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// полностью заменяет заказ вместе с доставкой, оплатой и товарами;
+// order.Version должна совпадать с текущей версией заказа в базе
+func (p *PgxDB) UpdateOrder(ctx context.Context, order *models.Order) (err error) {
+	ctx, done := p.startQuery(ctx, "update_order")
+	defer done()
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(ctx, tx, &err)
+
+	before, err := lockOrderPgx(ctx, tx, order.OrderUID)
+	if err != nil {
+		return err
+	}
+
+	var version int
+	var status models.OrderStatus
+	err = tx.QueryRow(ctx, `
+        UPDATE orders SET track_number = $3, entry = $4, locale = $5, internal_signature = $6,
+        customer_id = $7, delivery_service = $8, shardkey = $9, sm_id = $10, date_created = $11,
+        oof_shard = $12, version = version + 1
+        WHERE order_uid = $1 AND version = $2 AND deleted_at IS NULL
+        RETURNING version, status
+    `, order.OrderUID, order.Version, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
+		order.CustomerID, order.DeliveryService, order.Shardkey, order.SmID, order.DateCreated, order.OofShard,
+	).Scan(&version, &status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return versionMismatchPgx(ctx, tx, order.OrderUID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update order: %w", err)
+	}
+
+	batch := &pgx.Batch{}
+	for _, table := range []string{"delivery", "payment", "items"} {
+		batch.Queue(`DELETE FROM `+table+` WHERE order_uid = $1`, order.OrderUID)
+	}
+	queueOrderDetails(batch, order)
+	if err = execBatch(ctx, tx, batch); err != nil {
+		return fmt.Errorf("failed to replace order details: %w", err)
+	}
+	if err = copyItems(ctx, tx, order); err != nil {
+		return err
+	}
+
+	order.Version = version
+	order.Status = status
+	if err = writeAuditPgx(ctx, tx, order.OrderUID, models.AuditUpdate, before, order); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// помечает заказ удаленным; version = 0 отключает проверку версии
+func (p *PgxDB) DeleteOrder(ctx context.Context, orderUID string, version int) (err error) {
+	ctx, done := p.startQuery(ctx, "delete_order")
+	defer done()
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollbackOnError(ctx, tx, &err)
+
+	before, err := lockOrderPgx(ctx, tx, orderUID)
+	if err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, `
+        UPDATE orders SET deleted_at = now(), version = version + 1
+        WHERE order_uid = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2)
+    `, orderUID, version)
+	if err != nil {
+		return fmt.Errorf("failed to delete order: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return versionMismatchPgx(ctx, tx, orderUID)
+	}
+	if err = writeAuditPgx(ctx, tx, orderUID, models.AuditDelete, before, nil); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// то же, что versionMismatch, для транзакции pgx
+func versionMismatchPgx(ctx context.Context, tx pgx.Tx, orderUID string) error {
+	var current int
+	err := tx.QueryRow(ctx,
+		`SELECT version FROM orders WHERE order_uid = $1 AND deleted_at IS NULL`, orderUID,
+	).Scan(&current)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %s", ErrOrderNotFound, orderUID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get order version: %w", err)
+	}
+	return fmt.Errorf("%w: %s has version %d", ErrVersionConflict, orderUID, current)
+}
+
+// получает журнал изменений заказа, включая удаленные заказы
+func (p *PgxDB) GetOrderHistory(ctx context.Context, orderUID string) ([]*models.AuditEntry, error) {
+	ctx, done := p.startQuery(ctx, "get_order_history")
+	defer done()
+	rows, err := p.pool.Query(ctx, `
+        SELECT id, order_uid, action, actor, source, diff, created_at
+        FROM order_audit
+        WHERE order_uid = $1
+        ORDER BY id
+    `, orderUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order history: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*models.AuditEntry{}
+	for rows.Next() {
+		entry := &models.AuditEntry{}
+		var diff []byte
+		err = rows.Scan(&entry.ID, &entry.OrderUID, &entry.Action, &entry.Actor, &entry.Source, &diff, &entry.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entry.Diff = diff
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate audit entries: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,60 @@
+package db_test
+
+import (
+	"L0WB/internal/db"
+	"L0WB/internal/db/dbtest"
+	"context"
+	"os"
+	"testing"
+)
+
+// строка подключения к тестовой базе со схемой из init.sql, например
+// "host=localhost user=postgres password=postgres dbname=l0wb_test sslmode=disable";
+// перед каждой проверкой таблицы очищаются, поэтому пользователю нужно право TRUNCATE
+const postgresDSNEnv = "L0WB_TEST_POSTGRES_DSN"
+
+const truncateSQL = `TRUNCATE orders, delivery, payment, items, order_status_history, order_audit RESTART IDENTITY CASCADE`
+
+func postgresDSN(t *testing.T) string {
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", postgresDSNEnv)
+	}
+	return dsn
+}
+
+func TestWbDB(t *testing.T) {
+	dsn := postgresDSN(t)
+	dbtest.Run(t, func(t *testing.T) db.Database {
+		store, err := db.NewPostgresDBFromDSN(dsn)
+		if err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		t.Cleanup(func() { store.Close() })
+		if _, err = store.ExecContext(context.Background(), truncateSQL); err != nil {
+			t.Fatalf("failed to truncate tables: %v", err)
+		}
+		return store
+	})
+}
+
+func TestPgxDB(t *testing.T) {
+	dsn := postgresDSN(t)
+	dbtest.Run(t, func(t *testing.T) db.Database {
+		store, err := db.NewPgxDBFromDSN(dsn)
+		if err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		t.Cleanup(func() { store.Close() })
+		// очищаем отдельным подключением, у PgxDB нет Exec наружу
+		clean, err := db.NewPostgresDBFromDSN(dsn)
+		if err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		defer clean.Close()
+		if _, err = clean.ExecContext(context.Background(), truncateSQL); err != nil {
+			t.Fatalf("failed to truncate tables: %v", err)
+		}
+		return store
+	})
+}
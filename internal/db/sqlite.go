@@ -0,0 +1,111 @@
+package db
+
+import (
+	"L0WB/internal/config"
+	"L0WB/internal/logger"
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultSQLitePath = "l0wb.db"
+
+//go:embed migrations/sqlite/*.sql
+var sqliteMigrations embed.FS
+
+// открывает файл SQLite (драйвер modernc.org/sqlite, без cgo), применяет недостающие
+// миграции и возвращает WbDB с теми же запросами, что и для Postgres
+func NewSQLiteDB(cfg *config.SQLiteConfig) (*WbDB, error) {
+	dbPath := cfg.Path
+	if dbPath == "" {
+		dbPath = defaultSQLitePath
+	}
+	busyTimeout := cfg.BusyTimeout
+	if busyTimeout <= 0 {
+		busyTimeout = 5 * time.Second
+	}
+	// пишущие транзакции сразу берут блокировку на запись (_txlock=immediate) и ждут
+	// друг друга до busy_timeout - это заменяет SELECT ... FOR UPDATE;
+	// _time_format=sqlite пишет время в формате, понятном функциям даты SQLite
+	dsn := fmt.Sprintf("file:%s?_txlock=immediate&_time_format=sqlite&_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(%d)",
+		dbPath, busyTimeout.Milliseconds())
+	dbConn, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error opening sqlite database: %w", err)
+	}
+	if err := migrateSQLite(context.Background(), dbConn); err != nil {
+		dbConn.Close()
+		return nil, err
+	}
+	return &WbDB{DB: dbConn, sqlite: true}, nil
+}
+
+// применяет по порядку миграции из migrations/sqlite, которых еще нет в schema_migrations;
+// номер миграции - число в начале имени файла
+func migrateSQLite(ctx context.Context, dbConn *sql.DB) error {
+	_, err := dbConn.ExecContext(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version INTEGER PRIMARY KEY,
+            applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	const dir = "migrations/sqlite"
+	files, err := fs.ReadDir(sqliteMigrations, dir)
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+	for _, file := range files {
+		prefix, _, _ := strings.Cut(file.Name(), "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return fmt.Errorf("invalid migration name %s: %w", file.Name(), err)
+		}
+		var applied int
+		err = dbConn.QueryRowContext(ctx, `SELECT COUNT(*) FROM schema_migrations WHERE version = $1`, version).Scan(&applied)
+		if err != nil {
+			return fmt.Errorf("failed to check migration %d: %w", version, err)
+		}
+		if applied > 0 {
+			continue
+		}
+
+		script, err := sqliteMigrations.ReadFile(path.Join(dir, file.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %w", file.Name(), err)
+		}
+		if err = applyMigration(ctx, dbConn, version, string(script)); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", file.Name(), err)
+		}
+		logger.FromContext(ctx).Info("sqlite migration applied", "version", version, "file", file.Name())
+	}
+	return nil
+}
+
+func applyMigration(ctx context.Context, dbConn *sql.DB, version int, script string) (err error) {
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	if _, err = tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
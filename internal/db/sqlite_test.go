@@ -0,0 +1,39 @@
+package db_test
+
+import (
+	"L0WB/internal/config"
+	"L0WB/internal/db"
+	"L0WB/internal/db/dbtest"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteDB(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T) db.Database {
+		store, err := db.NewSQLiteDB(&config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "test.db")})
+		if err != nil {
+			t.Fatalf("failed to open sqlite: %v", err)
+		}
+		t.Cleanup(func() { store.Close() })
+		return store
+	})
+}
+
+// повторное открытие файла не применяет миграции заново
+func TestSQLiteMigrationsIdempotent(t *testing.T) {
+	cfg := &config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "test.db")}
+	for i := 0; i < 2; i++ {
+		store, err := db.NewSQLiteDB(cfg)
+		if err != nil {
+			t.Fatalf("open #%d: %v", i+1, err)
+		}
+		var applied int
+		if err = store.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied); err != nil {
+			t.Fatalf("failed to count migrations: %v", err)
+		}
+		if applied != 1 {
+			t.Errorf("open #%d: %d migrations recorded, want 1", i+1, applied)
+		}
+		store.Close()
+	}
+}
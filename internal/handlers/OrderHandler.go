@@ -4,16 +4,57 @@ import (
 	"L0WB/internal/cache"
 	"L0WB/internal/config"
 	"L0WB/internal/db"
+	"L0WB/internal/kafka"
+	"L0WB/internal/logger"
+	"L0WB/internal/models"
+	"L0WB/internal/schema"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/gorilla/mux"
-	"log"
+	kafkago "github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"io"
 	"net/http"
+	"strconv"
+)
+
+const (
+	maxOrderBodySize = 1 << 20
+	maxBatchBodySize = 16 << 20
+	maxBatchSize     = 1000
 )
 
 type OrderHandler struct {
 	*BaseHandler
 }
 
+// результат обработки одного заказа из пакета
+type BatchItemResult struct {
+	Index      int                    `json:"index"`
+	OrderUID   string                 `json:"order_uid,omitempty"`
+	Status     int                    `json:"status"`
+	Error      string                 `json:"error,omitempty"`
+	Details    []string               `json:"details,omitempty"`
+	Violations kafka.ValidationErrors `json:"violations,omitempty"`
+	Warnings   kafka.ValidationErrors `json:"warnings,omitempty"`
+}
+
+// результат проверки заказа без сохранения
+type ValidationResult struct {
+	Valid      bool                   `json:"valid"`
+	Violations kafka.ValidationErrors `json:"violations,omitempty"`
+	Warnings   kafka.ValidationErrors `json:"warnings,omitempty"`
+}
+
+type BatchResponse struct {
+	Created int               `json:"created"`
+	Failed  int               `json:"failed"`
+	Results []BatchItemResult `json:"results"`
+}
+
 func NewProductHandler(db db.Database, config *config.AppConfig, Cache *cache.Cache) *OrderHandler {
 	return &OrderHandler{&BaseHandler{DB: db, Config: config, Cache: Cache}}
 }
@@ -22,18 +63,371 @@ func (h *OrderHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	orderId := vars["order_id"]
 	order, ok := (*h.Cache).Get(orderId)
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.Bool("cache.hit", ok))
 	if ok {
-		ResponseWithJSON(w, http.StatusOK, order)
-		log.Println("Get order from cache")
+		logger.FromContext(r.Context()).Debug("order served from cache", logger.KeyOrderUID, orderId)
+		writeOrder(w, r, order)
+		return
+	}
+	order, err := h.DB.GetOrder(r.Context(), orderId)
+	switch {
+	case errors.Is(err, db.ErrOrderNotFound):
+		ResponseWithError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, db.ErrUnavailable):
+		ResponseWithError(w, http.StatusServiceUnavailable, "order is not cached and database is unavailable")
+		return
+	case errors.Is(err, context.DeadlineExceeded):
+		ResponseWithError(w, http.StatusGatewayTimeout, "database query timed out")
+		return
+	case err != nil:
+		logger.FromContext(r.Context()).Error("failed to get order", logger.KeyOrderUID, orderId, "error", err)
+		ResponseWithError(w, http.StatusInternalServerError, "failed to get order")
+		return
+	}
+	logger.FromContext(r.Context()).Debug("order served from db", logger.KeyOrderUID, orderId)
+	(*h.Cache).Add(orderId, order)
+	writeOrder(w, r, order)
+	return
+}
+
+// отдает заказ с ETag, либо 304, если у клиента актуальная версия
+func writeOrder(w http.ResponseWriter, r *http.Request, order *models.Order) {
+	w.Header().Set("ETag", orderETag(order.Version))
+	if matchesIfNoneMatch(r, order.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	ResponseWithJSON(w, http.StatusOK, order)
+}
+
+// действующие настройки топика заказов: HTTP API проверяет заказы так же, как его консьюмер
+func (h *OrderHandler) orderTopic() config.TopicSettings {
+	return h.Config.TopicSettings(h.Config.Kafka.Topic)
+}
+
+// принимает один заказ в том же формате, что и сообщение в Kafka
+func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	var order models.Order
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxOrderBodySize))
+	if err := dec.Decode(&order); err != nil {
+		ResponseWithBodyError(w, err, "invalid order JSON")
+		return
+	}
+	res := h.createOrder(r.Context(), &order)
+	if res.Error != "" {
+		ResponseWithJSON(w, res.Status, ErrorResponse{Error: res.Error, Details: res.Details, Violations: res.Violations})
+		return
+	}
+	w.Header().Set("ETag", orderETag(order.Version))
+	ResponseWithJSON(w, http.StatusCreated, order)
+}
+
+// принимает массив заказов и возвращает результат по каждому из них
+func (h *OrderHandler) CreateOrdersBatch(w http.ResponseWriter, r *http.Request) {
+	var raw []json.RawMessage
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodySize))
+	if err := dec.Decode(&raw); err != nil {
+		ResponseWithBodyError(w, err, "invalid batch JSON, expected array of orders")
 		return
 	}
-	order, err := h.DB.GetOrder(context.Background(), orderId)
+	if len(raw) == 0 {
+		ResponseWithError(w, http.StatusBadRequest, "empty batch")
+		return
+	}
+	if len(raw) > maxBatchSize {
+		ResponseWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("batch too large: %d orders (max %d)", len(raw), maxBatchSize))
+		return
+	}
+
+	resp := BatchResponse{Results: make([]BatchItemResult, 0, len(raw))}
+	for i, msg := range raw {
+		var order models.Order
+		var res BatchItemResult
+		if err := json.Unmarshal(msg, &order); err != nil {
+			res = BatchItemResult{Status: http.StatusBadRequest, Error: "invalid order JSON", Details: []string{err.Error()}}
+		} else {
+			res = h.createOrder(r.Context(), &order)
+		}
+		res.Index = i
+		if res.Status == http.StatusCreated {
+			resp.Created++
+		} else {
+			resp.Failed++
+		}
+		resp.Results = append(resp.Results, res)
+	}
+	ResponseWithJSON(w, http.StatusOK, resp)
+}
+
+// валидирует, сохраняет заказ и добавляет его в кэш
+func (h *OrderHandler) createOrder(ctx context.Context, order *models.Order) BatchItemResult {
+	ctx = logger.With(ctx, logger.KeyOrderUID, order.OrderUID)
+	res := BatchItemResult{OrderUID: order.OrderUID}
+	settings := h.orderTopic()
+	warnings, err := kafka.ValidateOrder(order, settings.Consistency)
 	if err != nil {
-		ResponseWithJSON(w, http.StatusNotFound, err.Error())
+		res.Status = http.StatusUnprocessableEntity
+		res.Error = "validation failed"
+		res.Violations = violations(err)
+		return res
+	}
+	if len(warnings) > 0 {
+		logger.FromContext(ctx).Warn("order is inconsistent", "warnings", warnings)
+		res.Warnings = warnings
+	}
+	if err = h.DB.CreateOrder(ctx, order); err != nil {
+		if errors.Is(err, db.ErrOrderExists) {
+			res.Status = http.StatusConflict
+			res.Error = err.Error()
+			return res
+		}
+		if errors.Is(err, db.ErrUnavailable) {
+			res.Status = http.StatusServiceUnavailable
+			res.Error = err.Error()
+			return res
+		}
+		if errors.Is(err, db.ErrInvalidStatus) {
+			res.Status = http.StatusUnprocessableEntity
+			res.Error = "validation failed"
+			res.Violations = kafka.ValidationErrors{{
+				Path: "status", Rule: kafka.RuleEnum, Value: order.Status,
+				Message: fmt.Sprintf("new order must have status %s", models.StatusCreated),
+			}}
+			return res
+		}
+		logger.FromContext(ctx).Error("failed to create order", "error", err)
+		res.Status = http.StatusInternalServerError
+		res.Error = "failed to create order"
+		return res
+	}
+	(*h.Cache).Add(order.OrderUID, order)
+	logger.FromContext(ctx).Info("order created via http")
+	res.Status = http.StatusCreated
+	return res
+}
+
+// переводит заказ в новый статус
+func (h *OrderHandler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	orderId := mux.Vars(r)["order_id"]
+	ctx := logger.With(r.Context(), logger.KeyOrderUID, orderId)
+	var update models.StatusUpdate
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxOrderBodySize))
+	if err := dec.Decode(&update); err != nil {
+		ResponseWithBodyError(w, err, "invalid status update JSON")
+		return
+	}
+	update.OrderUID = orderId
+	if err := kafka.ValidStatusUpdate(&update); err != nil {
+		ResponseWithValidationError(w, err)
+		return
+	}
+
+	version, _, err := parseIfMatch(r)
+	if err != nil {
+		ResponseWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.DB.UpdateOrderStatus(ctx, orderId, update.Status, update.Reason, version)
+	switch {
+	case errors.Is(err, db.ErrOrderNotFound):
+		ResponseWithError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, db.ErrUnavailable):
+		ResponseWithError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	case errors.Is(err, db.ErrVersionConflict):
+		ResponseWithError(w, http.StatusPreconditionFailed, err.Error())
+		return
+	case errors.Is(err, db.ErrInvalidTransition):
+		ResponseWithError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		logger.FromContext(ctx).Error("failed to update order status", "error", err)
+		ResponseWithError(w, http.StatusInternalServerError, "failed to update order status")
+		return
+	}
+
+	order, err := h.DB.GetOrder(ctx, orderId)
+	if err != nil {
+		(*h.Cache).Remove(orderId)
+		ResponseWithError(w, http.StatusInternalServerError, "failed to load updated order")
 		return
 	}
-	log.Println("Get order from DB")
 	(*h.Cache).Add(orderId, order)
+	logger.FromContext(ctx).Info("order status changed via http", "status", update.Status)
+	w.Header().Set("ETag", orderETag(order.Version))
 	ResponseWithJSON(w, http.StatusOK, order)
-	return
+}
+
+// полностью заменяет заказ; текущая версия передается в If-Match или в теле
+func (h *OrderHandler) UpdateOrder(w http.ResponseWriter, r *http.Request) {
+	orderId := mux.Vars(r)["order_id"]
+	ctx := logger.With(r.Context(), logger.KeyOrderUID, orderId)
+	var order models.Order
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxOrderBodySize))
+	if err := dec.Decode(&order); err != nil {
+		ResponseWithBodyError(w, err, "invalid order JSON")
+		return
+	}
+	if order.OrderUID == "" {
+		order.OrderUID = orderId
+	}
+	if order.OrderUID != orderId {
+		ResponseWithError(w, http.StatusBadRequest, "order_uid in body does not match URL")
+		return
+	}
+	version, ifMatch, err := parseIfMatch(r)
+	if err != nil {
+		ResponseWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if ifMatch {
+		order.Version = version
+	}
+	if order.Version <= 0 {
+		ResponseWithValidationError(w, kafka.ValidationErrors{{
+			Path: "version", Rule: kafka.RuleRequired, Value: order.Version,
+			Message: "version is required (in body or If-Match)",
+		}})
+		return
+	}
+	warnings, err := kafka.ValidateOrder(&order, h.orderTopic().Consistency)
+	if err != nil {
+		ResponseWithValidationError(w, err)
+		return
+	}
+	if len(warnings) > 0 {
+		logger.FromContext(ctx).Warn("order is inconsistent", "warnings", warnings)
+	}
+
+	err = h.DB.UpdateOrder(ctx, &order)
+	switch {
+	case errors.Is(err, db.ErrOrderNotFound):
+		ResponseWithError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, db.ErrUnavailable):
+		ResponseWithError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	case errors.Is(err, db.ErrVersionConflict):
+		ResponseWithError(w, conflictStatus(ifMatch), err.Error())
+		return
+	case err != nil:
+		logger.FromContext(ctx).Error("failed to update order", "error", err)
+		ResponseWithError(w, http.StatusInternalServerError, "failed to update order")
+		return
+	}
+	(*h.Cache).Add(orderId, &order)
+	logger.FromContext(ctx).Info("order updated", "version", order.Version)
+	w.Header().Set("ETag", orderETag(order.Version))
+	ResponseWithJSON(w, http.StatusOK, &order)
+}
+
+// мягко удаляет заказ; версию для проверки можно передать в If-Match или параметре version
+func (h *OrderHandler) DeleteOrder(w http.ResponseWriter, r *http.Request) {
+	orderId := mux.Vars(r)["order_id"]
+	ctx := logger.With(r.Context(), logger.KeyOrderUID, orderId)
+	version := 0
+	if v := r.URL.Query().Get("version"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			ResponseWithError(w, http.StatusBadRequest, "invalid version: "+v)
+			return
+		}
+		version = parsed
+	}
+	ifMatchVersion, ifMatch, err := parseIfMatch(r)
+	if err != nil {
+		ResponseWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if ifMatch {
+		version = ifMatchVersion
+	}
+
+	err = h.DB.DeleteOrder(ctx, orderId, version)
+	switch {
+	case errors.Is(err, db.ErrOrderNotFound):
+		ResponseWithError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, db.ErrUnavailable):
+		ResponseWithError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	case errors.Is(err, db.ErrVersionConflict):
+		ResponseWithError(w, conflictStatus(ifMatch), err.Error())
+		return
+	case err != nil:
+		logger.FromContext(ctx).Error("failed to delete order", "error", err)
+		ResponseWithError(w, http.StatusInternalServerError, "failed to delete order")
+		return
+	}
+	(*h.Cache).Remove(orderId)
+	logger.FromContext(ctx).Info("order deleted")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// конфликт версий по If-Match - это 412, по версии из тела или параметра - 409
+func conflictStatus(ifMatch bool) int {
+	if ifMatch {
+		return http.StatusPreconditionFailed
+	}
+	return http.StatusConflict
+}
+
+// отдает журнал изменений заказа
+func (h *OrderHandler) GetOrderHistory(w http.ResponseWriter, r *http.Request) {
+	orderId := mux.Vars(r)["order_id"]
+	ctx := logger.With(r.Context(), logger.KeyOrderUID, orderId)
+	entries, err := h.DB.GetOrderHistory(ctx, orderId)
+	if errors.Is(err, db.ErrUnavailable) {
+		ResponseWithError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to get order history", "error", err)
+		ResponseWithError(w, http.StatusInternalServerError, "failed to get order history")
+		return
+	}
+	if len(entries) == 0 {
+		ResponseWithError(w, http.StatusNotFound, fmt.Sprintf("%s: %s", db.ErrOrderNotFound, orderId))
+		return
+	}
+	ResponseWithJSON(w, http.StatusOK, entries)
+}
+
+// отдает JSON Schema сообщения с заказом
+func (h *OrderHandler) GetOrderSchema(w http.ResponseWriter, r *http.Request) {
+	ResponseWithJSON(w, http.StatusOK, schema.Order())
+}
+
+// проверяет заказ так же, как консьюмер топика заказов, но ничего не сохраняет
+func (h *OrderHandler) ValidateOrder(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxOrderBodySize))
+	if err != nil {
+		ResponseWithBodyError(w, err, "failed to read request body")
+		return
+	}
+
+	// разбор тот же, что у консьюмера топика заказов: версия схемы из заголовка
+	// Schema-Version или поля schema_version, апкастинг и JSON Schema, если она включена
+	settings := h.orderTopic()
+	msg := kafkago.Message{Value: body}
+	if v := r.Header.Get(kafka.HeaderSchemaVersion); v != "" {
+		msg.Headers = []kafkago.Header{{Key: kafka.HeaderSchemaVersion, Value: []byte(v)}}
+	}
+	res := ValidationResult{}
+	order, err := kafka.JSONDecoder{SchemaValidation: settings.SchemaValidation}.Decode(msg)
+	if err != nil {
+		res.Violations = decodeViolations(err)
+		ResponseWithJSON(w, http.StatusOK, res)
+		return
+	}
+	warnings, err := kafka.ValidateOrder(order, settings.Consistency)
+	if err != nil {
+		res.Violations = violations(err)
+	}
+	res.Warnings = warnings
+	res.Valid = len(res.Violations) == 0
+	ResponseWithJSON(w, http.StatusOK, res)
 }
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"L0WB/internal/cache"
+	"L0WB/internal/config"
+	"L0WB/internal/db"
+	"L0WB/internal/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// обработчик поверх MemoryDB, в которой уже есть заказ existing
+func newCreateHandler(t *testing.T) (*OrderHandler, *cache.Cache) {
+	t.Helper()
+	store := db.NewMemoryDB()
+	var existing models.Order
+	if err := json.Unmarshal(validOrderJSON(t, func(o *models.Order) { o.OrderUID = "existing" }), &existing); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreateOrder(context.Background(), &existing); err != nil {
+		t.Fatal(err)
+	}
+	c := cache.NewLRUCache(10)
+	return NewProductHandler(store, &config.AppConfig{}, &c), &c
+}
+
+func withUID(uid string) func(o *models.Order) {
+	return func(o *models.Order) { o.OrderUID = uid }
+}
+
+func TestCreateOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       func(t *testing.T) []byte
+		wantStatus int
+		wantPaths  []string
+	}{
+		{
+			name:       "created",
+			body:       func(t *testing.T) []byte { return validOrderJSON(t, withUID("new")) },
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "validation failure",
+			body: func(t *testing.T) []byte {
+				return validOrderJSON(t, func(o *models.Order) { o.OrderUID, o.Payment.Bank = "new", "" })
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantPaths:  []string{"payment.bank"},
+		},
+		{
+			name: "status other than created",
+			body: func(t *testing.T) []byte {
+				return validOrderJSON(t, func(o *models.Order) { o.OrderUID, o.Status = "new", models.StatusPaid })
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantPaths:  []string{"status"},
+		},
+		{
+			name:       "duplicate",
+			body:       func(t *testing.T) []byte { return validOrderJSON(t, withUID("existing")) },
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "malformed JSON",
+			body:       func(t *testing.T) []byte { return []byte(`{"order_uid":`) },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "oversized body",
+			body:       func(t *testing.T) []byte { return bytes.Repeat([]byte(" "), maxOrderBodySize+1) },
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, c := newCreateHandler(t)
+			w := httptest.NewRecorder()
+			h.CreateOrder(w, httptest.NewRequest(http.MethodPost, "/api/v1/orders", bytes.NewReader(tt.body(t))))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus == http.StatusCreated {
+				if etag := w.Header().Get("ETag"); etag != `"v1"` {
+					t.Errorf("ETag = %q, want %q", etag, `"v1"`)
+				}
+				if order, ok := (*c).Get("new"); !ok || order.Status != models.StatusCreated {
+					t.Errorf("cached order = %+v, %v", order, ok)
+				}
+				return
+			}
+			var resp ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Error == "" {
+				t.Error("error is empty")
+			}
+			if got := paths(resp.Violations); !reflect.DeepEqual(got, tt.wantPaths) {
+				t.Errorf("violations = %q, want %q", got, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestCreateOrdersBatch(t *testing.T) {
+	batch := func(t *testing.T, items ...[]byte) []byte {
+		t.Helper()
+		return append(append([]byte("["), bytes.Join(items, []byte(","))...), ']')
+	}
+	tests := []struct {
+		name        string
+		body        func(t *testing.T) []byte
+		wantStatus  int
+		wantResults []int
+	}{
+		{
+			name: "all created",
+			body: func(t *testing.T) []byte {
+				return batch(t, validOrderJSON(t, withUID("a")), validOrderJSON(t, withUID("b")))
+			},
+			wantStatus:  http.StatusOK,
+			wantResults: []int{http.StatusCreated, http.StatusCreated},
+		},
+		{
+			name: "per-order results",
+			body: func(t *testing.T) []byte {
+				return batch(t,
+					validOrderJSON(t, withUID("a")),
+					validOrderJSON(t, func(o *models.Order) { o.OrderUID, o.Payment.Bank = "b", "" }),
+					validOrderJSON(t, withUID("existing")),
+					validOrderJSON(t, withUID("a")),
+					[]byte(`"not an order"`),
+				)
+			},
+			wantStatus: http.StatusOK,
+			wantResults: []int{
+				http.StatusCreated, http.StatusUnprocessableEntity, http.StatusConflict,
+				http.StatusConflict, http.StatusBadRequest,
+			},
+		},
+		{
+			name:       "not an array",
+			body:       func(t *testing.T) []byte { return validOrderJSON(t, nil) },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "empty",
+			body:       func(t *testing.T) []byte { return []byte(`[]`) },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "too many orders",
+			body: func(t *testing.T) []byte {
+				return []byte("[" + strings.Repeat("{},", maxBatchSize) + "{}]")
+			},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "oversized body",
+			body:       func(t *testing.T) []byte { return bytes.Repeat([]byte(" "), maxBatchBodySize+1) },
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newCreateHandler(t)
+			w := httptest.NewRecorder()
+			h.CreateOrdersBatch(w, httptest.NewRequest(http.MethodPost, "/api/v1/orders:batch", bytes.NewReader(tt.body(t))))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp BatchResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			var got []int
+			created := 0
+			for i, res := range resp.Results {
+				if res.Index != i {
+					t.Errorf("result %d has index %d", i, res.Index)
+				}
+				if res.Status == http.StatusCreated {
+					created++
+				}
+				got = append(got, res.Status)
+			}
+			if !reflect.DeepEqual(got, tt.wantResults) {
+				t.Errorf("results = %v, want %v", got, tt.wantResults)
+			}
+			if resp.Created != created || resp.Failed != len(got)-created {
+				t.Errorf("created = %d, failed = %d; want %d, %d", resp.Created, resp.Failed, created, len(got)-created)
+			}
+		})
+	}
+}
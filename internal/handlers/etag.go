@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ETag заказа строится из его версии
+func orderETag(version int) string {
+	return fmt.Sprintf(`"v%d"`, version)
+}
+
+// проверяет, совпадает ли ETag из If-None-Match с текущей версией
+func matchesIfNoneMatch(r *http.Request, version int) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	current := orderETag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+	return false
+}
+
+// разбирает If-Match и возвращает ожидаемую версию заказа;
+// ok = false, если заголовка нет или в нем "*"
+func parseIfMatch(r *http.Request) (version int, ok bool, err error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, false, nil
+	}
+	if strings.Contains(header, ",") {
+		return 0, false, fmt.Errorf("If-Match must contain a single ETag or *")
+	}
+	if strings.HasPrefix(header, "W/") {
+		return 0, false, fmt.Errorf("weak ETag is not allowed in If-Match: %s", header)
+	}
+	// ETag строго в форме "v<версия>": в кавычках и только с цифрами после v
+	if len(header) < 4 || !strings.HasPrefix(header, `"v`) || !strings.HasSuffix(header, `"`) {
+		return 0, false, fmt.Errorf("invalid ETag in If-Match: %s", header)
+	}
+	n, err := strconv.ParseUint(header[2:len(header)-1], 10, 31)
+	if err != nil || n == 0 {
+		return 0, false, fmt.Errorf("invalid ETag in If-Match: %s", header)
+	}
+	return int(n), true, nil
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header      string
+		wantVersion int
+		wantOK      bool
+		wantErr     bool
+	}{
+		{header: ""},
+		{header: "*"},
+		{header: " * "},
+		{header: `"v1"`, wantVersion: 1, wantOK: true},
+		{header: ` "v42" `, wantVersion: 42, wantOK: true},
+		{header: `"v2147483647"`, wantVersion: 2147483647, wantOK: true},
+		{header: `"v2147483648"`, wantErr: true},
+		{header: `"v0"`, wantErr: true},
+		{header: `"v-1"`, wantErr: true},
+		{header: `"v+1"`, wantErr: true},
+		{header: `"v"`, wantErr: true},
+		{header: `"1"`, wantErr: true},
+		{header: `v1`, wantErr: true},
+		{header: `"v1`, wantErr: true},
+		{header: `W/"v1"`, wantErr: true},
+		{header: `"v1", "v2"`, wantErr: true},
+		{header: `*, "v1"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+			version, ok, err := parseIfMatch(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseIfMatch(%q) error = %v, wantErr %v", tt.header, err, tt.wantErr)
+			}
+			if version != tt.wantVersion || ok != tt.wantOK {
+				t.Errorf("parseIfMatch(%q) = %d, %v; want %d, %v", tt.header, version, ok, tt.wantVersion, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestMatchesIfNoneMatch(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: "", want: false},
+		{header: `"v3"`, want: true},
+		{header: `"v2"`, want: false},
+		{header: `W/"v3"`, want: true},
+		{header: "*", want: true},
+		{header: `"v1", W/"v3"`, want: true},
+		{header: `"v1","v2"`, want: false},
+		{header: `v3`, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("If-None-Match", tt.header)
+			}
+			if got := matchesIfNoneMatch(r, 3); got != tt.want {
+				t.Errorf("matchesIfNoneMatch(%q, 3) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
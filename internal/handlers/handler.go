@@ -4,7 +4,9 @@ import (
 	"L0WB/internal/cache"
 	"L0WB/internal/config"
 	"L0WB/internal/db"
+	"L0WB/internal/kafka"
 	"encoding/json"
+	"errors"
 	"net/http"
 )
 
@@ -14,6 +16,13 @@ type BaseHandler struct {
 	Cache  *cache.Cache
 }
 
+// тело ответа с ошибкой
+type ErrorResponse struct {
+	Error      string                 `json:"error"`
+	Details    []string               `json:"details,omitempty"`
+	Violations kafka.ValidationErrors `json:"violations,omitempty"`
+}
+
 func NewBaseHandler(DB db.Database, Config *config.AppConfig, Cache *cache.Cache) *BaseHandler {
 	return &BaseHandler{
 		DB:     DB,
@@ -28,3 +37,45 @@ func ResponseWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.WriteHeader(code)
 	w.Write(response)
 }
+
+func ResponseWithError(w http.ResponseWriter, code int, message string, details ...string) {
+	ResponseWithJSON(w, code, ErrorResponse{Error: message, Details: details})
+}
+
+// отвечает на ошибку чтения тела запроса: 413, если тело больше лимита, иначе 400
+func ResponseWithBodyError(w http.ResponseWriter, err error, message string) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		ResponseWithError(w, http.StatusRequestEntityTooLarge, "request body too large", err.Error())
+		return
+	}
+	ResponseWithError(w, http.StatusBadRequest, message, err.Error())
+}
+
+// отвечает 422 со списком всех нарушений валидации
+func ResponseWithValidationError(w http.ResponseWriter, err error) {
+	ResponseWithJSON(w, http.StatusUnprocessableEntity, ErrorResponse{
+		Error:      "validation failed",
+		Violations: violations(err),
+	})
+}
+
+// нарушения из ошибки декодера: несоответствие JSON Schema или неразбираемый документ
+func decodeViolations(err error) kafka.ValidationErrors {
+	var errs kafka.ValidationErrors
+	if errors.As(err, &errs) {
+		return errs
+	}
+	if errors.Is(err, kafka.ErrUnsupportedSchemaVersion) {
+		return kafka.ValidationErrors{{Path: kafka.SchemaVersionField, Rule: kafka.RuleSchema, Message: err.Error()}}
+	}
+	return kafka.ValidationErrors{{Rule: kafka.RuleSchema, Message: err.Error()}}
+}
+
+func violations(err error) kafka.ValidationErrors {
+	var errs kafka.ValidationErrors
+	if errors.As(err, &errs) {
+		return errs
+	}
+	return kafka.ValidationErrors{{Message: err.Error()}}
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"L0WB/internal/health"
+	"net/http"
+)
+
+type HealthHandler struct {
+	Readiness []health.Check
+}
+
+func NewHealthHandler(readiness []health.Check) *HealthHandler {
+	return &HealthHandler{Readiness: readiness}
+}
+
+// процесс жив и обслуживает HTTP; зависимости не проверяются
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	ResponseWithJSON(w, http.StatusOK, health.Report{Status: health.StatusOK})
+}
+
+// сервис готов принимать трафик: 200, если все зависимости доступны, иначе 503
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	report := health.Run(r.Context(), h.Readiness)
+	code := http.StatusOK
+	if !report.OK() {
+		code = http.StatusServiceUnavailable
+	}
+	ResponseWithJSON(w, code, report)
+}
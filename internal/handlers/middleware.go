@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"L0WB/internal/db"
+	"L0WB/internal/logger"
+	"L0WB/internal/metrics"
+	"L0WB/internal/tracing"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	// инициатор изменения для журнала; сервис не аутентифицирует клиентов, поэтому
+	// значение ничем не подтверждено и пишется в журнал с пометкой и адресом клиента
+	ActorHeader = "X-Actor"
+)
+
+// длина значения X-Actor, которое попадает в журнал (колонка actor - VARCHAR(255))
+const maxActorLength = 128
+
+// допустимый X-Request-ID клиента: он попадает в ответ, логи и order_audit.source
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type requestIDKey struct{}
+
+// присваивает запросу ID (или берет его из X-Request-ID, если он подходит под
+// requestIDPattern) и кладет в контекст сведения для журнала изменений
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		ctx := context.WithValue(r.Context(), requestIDKey{}, requestID)
+		ctx = logger.With(ctx, logger.KeyRequestID, requestID)
+		ctx = db.WithAudit(ctx, db.AuditInfo{Actor: requestActor(r), Source: "http:" + requestID})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// инициатор для журнала изменений: без X-Actor - anonymous@<адрес>, иначе
+// заявленное имя с пометкой unverified и адресом, с которого пришел запрос
+func requestActor(r *http.Request) string {
+	addr := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	claimed := strings.TrimSpace(r.Header.Get(ActorHeader))
+	if claimed == "" {
+		return "anonymous@" + addr
+	}
+	if runes := []rune(claimed); len(runes) > maxActorLength {
+		claimed = string(runes[:maxActorLength])
+	}
+	return fmt.Sprintf("%s (unverified, %s)", claimed, addr)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// открывает серверный спан запроса, продолжая трейс из заголовка traceparent
+func TracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r)
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route),
+			))
+		defer span.End()
+		if traceID := tracing.TraceID(ctx); traceID != "" {
+			ctx = logger.With(ctx, logger.KeyTraceID, traceID)
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+		span.SetAttributes(attribute.Int("http.response.status_code", rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+	})
+}
+
+// шаблон маршрута mux, чтобы не плодить метки и имена спанов на каждый order_id
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if tpl, err := current.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return "unknown"
+}
+
+// пишет метрики запроса по шаблону маршрута
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(rec, r)
+		metrics.HTTPDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+		metrics.HTTPRequests.WithLabelValues(route, r.Method, strconv.Itoa(rec.status)).Inc()
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestActor(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     string
+		want       string
+	}{
+		{name: "anonymous", remoteAddr: "10.0.0.1:5123", want: "anonymous@10.0.0.1"},
+		{name: "blank header", remoteAddr: "10.0.0.1:5123", header: "  ", want: "anonymous@10.0.0.1"},
+		{name: "ipv6", remoteAddr: "[::1]:5123", want: "anonymous@::1"},
+		{name: "address without port", remoteAddr: "pipe", want: "anonymous@pipe"},
+		{name: "claimed", remoteAddr: "10.0.0.1:5123", header: "alice", want: "alice (unverified, 10.0.0.1)"},
+		{
+			name:       "long claim truncated",
+			remoteAddr: "10.0.0.1:5123",
+			header:     strings.Repeat("я", maxActorLength+10),
+			want:       strings.Repeat("я", maxActorLength) + " (unverified, 10.0.0.1)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.header != "" {
+				r.Header.Set(ActorHeader, tt.header)
+			}
+			if got := requestActor(r); got != tt.want {
+				t.Errorf("requestActor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{name: "client id", header: "req-1.A_b", keep: true},
+		{name: "max length", header: strings.Repeat("a", 64), keep: true},
+		{name: "missing"},
+		{name: "oversized", header: strings.Repeat("a", 300)},
+		{name: "one over max length", header: strings.Repeat("a", 65)},
+		{name: "bad characters", header: "id\" injected=1"},
+		{name: "non-ascii", header: "запрос"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = RequestIDFromContext(r.Context())
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set(RequestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			got := w.Header().Get(RequestIDHeader)
+			if got != seen {
+				t.Errorf("response id %q differs from context id %q", got, seen)
+			}
+			if tt.keep && got != tt.header {
+				t.Errorf("id = %q, want client id %q", got, tt.header)
+			}
+			if !tt.keep && (got == tt.header || !requestIDPattern.MatchString(got)) {
+				t.Errorf("id = %q, want a newly generated id", got)
+			}
+		})
+	}
+}
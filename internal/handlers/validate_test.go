@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"L0WB/internal/config"
+	"L0WB/internal/kafka"
+	"L0WB/internal/models"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// заказ, проходящий правила по умолчанию и проверки согласованности
+func validOrderJSON(t *testing.T, modify func(o *models.Order)) []byte {
+	t.Helper()
+	order := models.Order{
+		OrderUID: "b563feb7b2b84b6test", TrackNumber: "WBILMTESTTRAC", Entry: "WBIL", Locale: "en",
+		CustomerID: "test", DeliveryService: "meest", Shardkey: "9", SmID: 99,
+		DateCreated: time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC), OofShard: "1",
+		Delivery: models.Delivery{
+			Name: "Test Testov", Phone: "+79161234567", Zip: "123456", City: "Moscow",
+			Address: "Ploshad Mira 15", Region: "Russia", Email: "test@gmail.com",
+		},
+		Payment: models.Payment{
+			TransactionNumber: "b563feb7b2b84b6test", Currency: "USD", Provider: "wbpay", Amount: 1818,
+			PaymentDT: 1637907727, Bank: "alpha", DeliveryCost: 1500, GoodsTotal: 317, CustomFee: 1,
+		},
+		Items: []models.Item{{
+			ChrtID: 9934930, TrackNumber: "WBILMTESTTRAC", Price: 453, RID: "ab4219087a764ae0bte",
+			ItemName: "Mascaras", Sale: 30, ItemSize: "0", TotalPrice: 317, NmID: 2389212,
+			Brand: "Vivienne Sabo", Status: 200,
+		}},
+	}
+	if modify != nil {
+		modify(&order)
+	}
+	raw, err := json.Marshal(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// добавляет поле в JSON заказа
+func withField(t *testing.T, raw []byte, field string) []byte {
+	t.Helper()
+	return append(raw[:len(raw)-1], []byte(","+field+"}")...)
+}
+
+// добавляет в JSON заказа поле, которого нет в схеме
+func withExtraField(t *testing.T, raw []byte) []byte {
+	t.Helper()
+	return withField(t, raw, `"extra":1`)
+}
+
+func TestValidateOrder(t *testing.T) {
+	inconsistent := func(o *models.Order) { o.Payment.Amount = 1 }
+	enabled := true
+
+	tests := []struct {
+		name             string
+		schemaValidation bool
+		consistency      string
+		headers          map[string]string
+		topic            *config.TopicConfig
+		body             func(t *testing.T) []byte
+		wantValid        bool
+		wantViolations   []string
+		wantWarnings     []string
+	}{
+		{
+			name:        "valid",
+			consistency: kafka.ConsistencyReject,
+			body:        func(t *testing.T) []byte { return validOrderJSON(t, nil) },
+			wantValid:   true,
+		},
+		{
+			name:           "field rules",
+			body:           func(t *testing.T) []byte { return validOrderJSON(t, func(o *models.Order) { o.Payment.Bank = "" }) },
+			wantViolations: []string{"payment.bank"},
+		},
+		{
+			name:             "schema checked when enabled",
+			schemaValidation: true,
+			body:             func(t *testing.T) []byte { return withExtraField(t, validOrderJSON(t, nil)) },
+			wantViolations:   []string{"extra"},
+		},
+		{
+			name:      "schema skipped when disabled",
+			body:      func(t *testing.T) []byte { return withExtraField(t, validOrderJSON(t, nil)) },
+			wantValid: true,
+		},
+		{
+			name:           "topic enables schema",
+			topic:          &config.TopicConfig{SchemaValidation: &enabled},
+			body:           func(t *testing.T) []byte { return withExtraField(t, validOrderJSON(t, nil)) },
+			wantViolations: []string{"extra"},
+		},
+		{
+			name:           "topic consistency",
+			consistency:    kafka.ConsistencyOff,
+			topic:          &config.TopicConfig{Consistency: &config.ConsistencyConfig{Mode: kafka.ConsistencyReject}},
+			body:           func(t *testing.T) []byte { return validOrderJSON(t, inconsistent) },
+			wantViolations: []string{"payment.amount"},
+		},
+		{
+			name:        "consistency off",
+			consistency: kafka.ConsistencyOff,
+			body:        func(t *testing.T) []byte { return validOrderJSON(t, inconsistent) },
+			wantValid:   true,
+		},
+		{
+			name:         "consistency flag",
+			consistency:  kafka.ConsistencyFlag,
+			body:         func(t *testing.T) []byte { return validOrderJSON(t, inconsistent) },
+			wantValid:    true,
+			wantWarnings: []string{"payment.amount"},
+		},
+		{
+			name:           "consistency reject",
+			consistency:    kafka.ConsistencyReject,
+			body:           func(t *testing.T) []byte { return validOrderJSON(t, inconsistent) },
+			wantViolations: []string{"payment.amount"},
+		},
+		{
+			name:             "malformed JSON reported once",
+			schemaValidation: true,
+			body:             func(t *testing.T) []byte { return []byte(`{"order_uid":`) },
+			wantViolations:   []string{""},
+		},
+		{
+			name:             "schema_version field is not an extra field",
+			schemaValidation: true,
+			body:             func(t *testing.T) []byte { return withField(t, validOrderJSON(t, nil), `"schema_version":2`) },
+			wantValid:        true,
+		},
+		{
+			name:      "v1 document is upcast",
+			body:      func(t *testing.T) []byte { return withField(t, validOrderJSON(t, nil), `"schema_version":1`) },
+			wantValid: true,
+		},
+		{
+			name:           "unsupported version in header",
+			headers:        map[string]string{"Schema-Version": "3"},
+			body:           func(t *testing.T) []byte { return validOrderJSON(t, nil) },
+			wantViolations: []string{"schema_version"},
+		},
+		{
+			name:           "unsupported version in field",
+			body:           func(t *testing.T) []byte { return withField(t, validOrderJSON(t, nil), `"schema_version":0`) },
+			wantViolations: []string{"schema_version"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.AppConfig{}
+			cfg.Kafka.SchemaValidation = tt.schemaValidation
+			cfg.Validation.Consistency.Mode = tt.consistency
+			cfg.Kafka.Topic = "orders"
+			if tt.topic != nil {
+				topic := *tt.topic
+				topic.Name, topic.Handler = "orders", kafka.HandlerOrder
+				cfg.Kafka.Topics = []config.TopicConfig{topic}
+			}
+			h := NewProductHandler(nil, cfg, nil)
+
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/orders:validate", bytes.NewReader(tt.body(t)))
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			h.ValidateOrder(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+			}
+			var res ValidationResult
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+			if res.Valid != tt.wantValid {
+				t.Errorf("valid = %v, want %v (%+v)", res.Valid, tt.wantValid, res)
+			}
+			if got := paths(res.Violations); !reflect.DeepEqual(got, tt.wantViolations) {
+				t.Errorf("violations = %q, want %q", got, tt.wantViolations)
+			}
+			if got := paths(res.Warnings); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("warnings = %q, want %q", got, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+// ошибка чтения тела
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestValidateOrderBodyErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body func() *http.Request
+		want int
+	}{
+		{
+			name: "too large",
+			body: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat(" ", maxOrderBodySize+1)))
+			},
+			want: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "read error",
+			body: func() *http.Request { return httptest.NewRequest(http.MethodPost, "/", failingReader{}) },
+			want: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewProductHandler(nil, &config.AppConfig{}, nil)
+			w := httptest.NewRecorder()
+			h.ValidateOrder(w, tt.body())
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func paths(errs kafka.ValidationErrors) []string {
+	var out []string
+	for _, e := range errs {
+		out = append(out, e.Path)
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func ok(context.Context) error { return nil }
+
+func fail(context.Context) error { return errors.New("connection refused") }
+
+// висит, пока не истечет таймаут проверки
+func hang(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     []Check
+		wantStatus string
+		wantChecks map[string]Result
+	}{
+		{name: "no checks", wantStatus: StatusOK, wantChecks: map[string]Result{}},
+		{
+			name:       "all ok",
+			checks:     []Check{{Name: "db", Func: ok}, {Name: "cache", Func: ok}},
+			wantStatus: StatusOK,
+			wantChecks: map[string]Result{"db": {Status: StatusOK}, "cache": {Status: StatusOK}},
+		},
+		{
+			name:       "one failed",
+			checks:     []Check{{Name: "db", Func: fail}, {Name: "cache", Func: ok}},
+			wantStatus: StatusFail,
+			wantChecks: map[string]Result{
+				"db":    {Status: StatusFail, Error: "connection refused"},
+				"cache": {Status: StatusOK},
+			},
+		},
+		{
+			name:       "timeout",
+			checks:     []Check{{Name: "kafka", Timeout: 10 * time.Millisecond, Func: hang}},
+			wantStatus: StatusFail,
+			wantChecks: map[string]Result{"kafka": {Status: StatusFail, Error: context.DeadlineExceeded.Error()}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Run(context.Background(), tt.checks)
+			if report.Status != tt.wantStatus || report.OK() != (tt.wantStatus == StatusOK) {
+				t.Errorf("Run() status = %q, want %q", report.Status, tt.wantStatus)
+			}
+			if len(report.Checks) != len(tt.wantChecks) {
+				t.Fatalf("Run() checks = %v, want %v", report.Checks, tt.wantChecks)
+			}
+			for name, want := range tt.wantChecks {
+				got := report.Checks[name]
+				if got.Status != want.Status || got.Error != want.Error {
+					t.Errorf("check %s = %+v, want %+v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+// проверки выполняются параллельно, поэтому общее время - как у самой долгой
+func TestRunParallel(t *testing.T) {
+	checks := make([]Check, 5)
+	for i := range checks {
+		checks[i] = Check{Name: string(rune('a' + i)), Timeout: 50 * time.Millisecond, Func: hang}
+	}
+	start := time.Now()
+	Run(context.Background(), checks)
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("Run() took %v, checks are not run in parallel", elapsed)
+	}
+}
@@ -0,0 +1,75 @@
+package kafka
+
+import (
+	"L0WB/internal/config"
+	"L0WB/internal/models"
+	"fmt"
+)
+
+// код правила для проверок согласованности заказа
+const RuleConsistency = "consistency"
+
+// режимы проверки согласованности
+const (
+	ConsistencyOff    = "off"
+	ConsistencyFlag   = "flag"
+	ConsistencyReject = "reject"
+)
+
+// проверяет, что суммы и трек-номера в заказе сходятся между собой
+func CheckConsistency(order *models.Order, cfg config.ConsistencyConfig) ValidationErrors {
+	var errs ValidationErrors
+	tol := cfg.Tolerance
+
+	goodsSum := 0
+	for i, item := range order.Items {
+		path := fmt.Sprintf("items[%d]", i)
+		expected := item.Price * (100 - item.Sale) / 100
+		if !withinTolerance(item.TotalPrice, expected, tol) {
+			errs.add(path+".total_price", RuleConsistency, item.TotalPrice,
+				"total_price must equal price*(100-sale)/100 = %d", expected)
+		}
+		if item.TrackNumber != order.TrackNumber {
+			errs.add(path+".track_number", RuleConsistency, item.TrackNumber,
+				"track_number must match order track_number %s", order.TrackNumber)
+		}
+		goodsSum += item.TotalPrice
+	}
+
+	p := order.Payment
+	if len(order.Items) > 0 && !withinTolerance(p.GoodsTotal, goodsSum, tol) && p.GoodsTotal != len(order.Items) {
+		errs.add("payment.goods_total", RuleConsistency, p.GoodsTotal,
+			"goods_total must equal sum of items total_price (%d) or number of items (%d)", goodsSum, len(order.Items))
+	}
+	expectedAmount := p.GoodsTotal + p.DeliveryCost + p.CustomFee
+	if !withinTolerance(p.Amount, expectedAmount, tol) {
+		errs.add("payment.amount", RuleConsistency, p.Amount,
+			"amount must equal goods_total + delivery_cost + custom_fee = %d", expectedAmount)
+	}
+	return errs
+}
+
+func withinTolerance(actual, expected, tolerance int) bool {
+	diff := actual - expected
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= tolerance
+}
+
+// полная проверка заказа: правила полей и согласованность в заданном режиме;
+// в режиме flag расхождения возвращаются как предупреждения и не мешают сохранению
+func ValidateOrder(order *models.Order, cfg config.ConsistencyConfig) (warnings ValidationErrors, err error) {
+	var errs ValidationErrors
+	if verr := ValidData(order); verr != nil {
+		errs = append(errs, verr.(ValidationErrors)...)
+	}
+
+	switch cfg.Mode {
+	case ConsistencyReject:
+		errs = append(errs, CheckConsistency(order, cfg)...)
+	case ConsistencyFlag:
+		warnings = CheckConsistency(order, cfg)
+	}
+	return warnings, errs.Err()
+}
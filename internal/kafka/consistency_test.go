@@ -0,0 +1,106 @@
+package kafka
+
+import (
+	"L0WB/internal/config"
+	"L0WB/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestCheckConsistency(t *testing.T) {
+	tests := []struct {
+		name      string
+		tolerance int
+		modify    func(o *models.Order)
+		want      []violation
+	}{
+		{
+			name:   "consistent",
+			modify: func(o *models.Order) {},
+		},
+		{
+			name:   "total_price rounds down",
+			modify: func(o *models.Order) { o.Items[0].Price = 454 },
+		},
+		{
+			name:   "total_price off by one",
+			modify: func(o *models.Order) { o.Items[0].TotalPrice = 318 },
+			want: []violation{
+				{"items[0].total_price", RuleConsistency},
+				{"payment.goods_total", RuleConsistency},
+			},
+		},
+		{
+			name:      "within tolerance",
+			tolerance: 1,
+			modify:    func(o *models.Order) { o.Items[0].TotalPrice = 318; o.Payment.Amount = 1817 },
+		},
+		{
+			name:      "outside tolerance",
+			tolerance: 1,
+			modify:    func(o *models.Order) { o.Payment.Amount = 1820 },
+			want:      []violation{{"payment.amount", RuleConsistency}},
+		},
+		{
+			name:   "item track number",
+			modify: func(o *models.Order) { o.Items[0].TrackNumber = "OTHER" },
+			want:   []violation{{"items[0].track_number", RuleConsistency}},
+		},
+		{
+			name: "goods_total as number of items",
+			modify: func(o *models.Order) {
+				o.Payment.GoodsTotal = 1
+				o.Payment.Amount = 1502
+			},
+		},
+		{
+			name:   "goods_total mismatch",
+			modify: func(o *models.Order) { o.Payment.GoodsTotal = 300; o.Payment.Amount = 1801 },
+			want:   []violation{{"payment.goods_total", RuleConsistency}},
+		},
+		{
+			name: "no items",
+			modify: func(o *models.Order) {
+				o.Items = nil
+				o.Payment.GoodsTotal = 0
+				o.Payment.Amount = 1501
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := validOrder()
+			tt.modify(order)
+			got := violationsOf(t, CheckConsistency(order, config.ConsistencyConfig{Tolerance: tt.tolerance}).Err())
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateOrderModes(t *testing.T) {
+	inconsistent := validOrder()
+	inconsistent.Payment.Amount = 1
+
+	tests := []struct {
+		mode         string
+		wantWarnings []violation
+		wantErrors   []violation
+	}{
+		{mode: ConsistencyOff},
+		{mode: ConsistencyFlag, wantWarnings: []violation{{"payment.amount", RuleConsistency}}},
+		{mode: ConsistencyReject, wantErrors: []violation{{"payment.amount", RuleConsistency}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			warnings, err := ValidateOrder(inconsistent, config.ConsistencyConfig{Mode: tt.mode})
+			if got := violationsOf(t, warnings.Err()); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("warnings = %v, want %v", got, tt.wantWarnings)
+			}
+			if got := violationsOf(t, err); !reflect.DeepEqual(got, tt.wantErrors) {
+				t.Errorf("errors = %v, want %v", got, tt.wantErrors)
+			}
+		})
+	}
+}
@@ -3,64 +3,378 @@ package kafka
 import (
 	"L0WB/internal/config"
 	"L0WB/internal/db"
+	"L0WB/internal/logger"
+	"L0WB/internal/metrics"
 	"L0WB/internal/models"
+	"L0WB/internal/tracing"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/segmentio/kafka-go"
-	"log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"strconv"
+	"sync"
+	"time"
 )
 
+// имена обработчиков топиков (config.TopicConfig.Handler)
+const (
+	HandlerOrder        = "order"
+	HandlerStatus       = "status"
+	HandlerCancellation = "cancellation"
+	HandlerPayment      = "payment"
+)
+
+// обрабатывает одно сообщение топика
+type MessageHandler func(ctx context.Context, msg kafka.Message) error
+
 type Consumer struct {
-	reader *kafka.Reader
-	db     db.Database
-}
-
-// создает нового консьюмера
-func NewConsumer(cfg config.KafkaConfig, db db.Database) (*Consumer, error) {
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:        []string{cfg.BrokerAddress},
-		GroupID:        cfg.GroupID,
-		Topic:          cfg.Topic,
-		CommitInterval: 0,
-		MaxAttempts:    3,
-	})
-	return &Consumer{reader: reader, db: db}, nil
+	topic      config.TopicSettings
+	readers    []*kafka.Reader
+	dialer     *kafka.Dialer
+	brokers    []string
+	db         db.Database
+	decoder    Decoder
+	deadLetter *kafka.Writer
 }
 
-// читает одно сообщение из Kafka
-func (c *Consumer) ReadMessage(ctx context.Context) (kafka.Message, error) {
-	msg, err := c.reader.ReadMessage(ctx)
+// создает консьюмера топика с его действующими настройками
+func NewTopicConsumer(cfg config.KafkaConfig, topic config.TopicSettings, db db.Database) (*Consumer, error) {
+	if topic.Name == "" {
+		return nil, fmt.Errorf("topic name is not configured")
+	}
+
+	c := &Consumer{topic: topic, db: db}
+	if topic.Handler == HandlerOrder {
+		decoder, err := newDecoder(cfg, topic)
+		if err != nil {
+			return nil, err
+		}
+		c.decoder = decoder
+	}
+	dialer, err := NewDialer(cfg)
 	if err != nil {
-		return kafka.Message{}, fmt.Errorf("failed to read message: %w", err)
+		return nil, err
+	}
+	c.dialer, c.brokers = dialer, cfg.BrokerList()
+	// читатели одной группы делят между собой партиции топика
+	for i := 0; i < topic.Concurrency; i++ {
+		c.readers = append(c.readers, kafka.NewReader(kafka.ReaderConfig{
+			Brokers:        cfg.BrokerList(),
+			GroupID:        topic.GroupID,
+			Topic:          topic.Name,
+			Dialer:         dialer,
+			CommitInterval: 0,
+			MaxAttempts:    3,
+		}))
+	}
+	if cfg.DeadLetterTopic != "" {
+		if c.deadLetter, err = NewWriter(cfg, cfg.DeadLetterTopic); err != nil {
+			return nil, err
+		}
 	}
-	return msg, nil
+	return c, nil
 }
 
-// закрывает Kafka-консьюмера
+// собирает декодеры всех поддерживаемых форматов; Protobuf и Avro
+// доступны, только если задан каталог реестра схем
+func newDecoder(cfg config.KafkaConfig, topic config.TopicSettings) (Decoder, error) {
+	decoders := map[string]Decoder{
+		FormatJSON: JSONDecoder{SchemaValidation: topic.SchemaValidation},
+	}
+	if cfg.SchemaRegistryDir != "" {
+		registry, err := LoadSchemaRegistry(cfg.SchemaRegistryDir)
+		if err != nil {
+			return nil, err
+		}
+		decoders[FormatProtobuf] = ProtobufDecoder{Registry: registry}
+		decoders[FormatAvro] = AvroDecoder{Registry: registry, Subject: topic.Name + "-value"}
+	}
+	format := cfg.PayloadFormat
+	if format == "" {
+		format = FormatJSON
+	}
+	if _, ok := decoders[format]; !ok {
+		return nil, fmt.Errorf("payload format %q is not available (is schema_registry_dir set?)", format)
+	}
+	return MultiDecoder{Default: format, Decoders: decoders}, nil
+}
+
+// настройки топика, который читает консьюмер
+func (c *Consumer) Topic() config.TopicSettings {
+	return c.topic
+}
+
+// проверяет, что хотя бы один брокер доступен и топик консьюмера существует
+func (c *Consumer) Ping(ctx context.Context) error {
+	var lastErr error
+	for _, broker := range c.brokers {
+		conn, err := c.dialer.DialContext(ctx, "tcp", broker)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+		_, err = conn.ReadPartitions(c.topic.Name)
+		conn.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read partitions of topic %s: %w", c.topic.Name, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("no kafka broker is reachable: %w", lastErr)
+}
+
+// читает топик всеми читателями и передает сообщения обработчику до отмены ctx
+func (c *Consumer) Run(ctx context.Context, handle MessageHandler) {
+	var wg sync.WaitGroup
+	for _, reader := range c.readers {
+		wg.Add(1)
+		go func(reader *kafka.Reader) {
+			defer wg.Done()
+			for {
+				msg, err := reader.ReadMessage(ctx)
+				if err != nil {
+					if ctx.Err() != nil {
+						logger.FromContext(ctx).Info("shutting down kafka consumer", logger.KeyTopic, c.topic.Name)
+						return
+					}
+					metrics.MessagesFailed.WithLabelValues(c.topic.Name, metrics.StageRead).Inc()
+					logger.FromContext(ctx).Error("failed to read message", logger.KeyTopic, c.topic.Name, "error", err)
+					continue
+				}
+				msgCtx, span := tracing.Start(ExtractTraceContext(ctx, msg), "kafka.consume "+msg.Topic,
+					trace.WithSpanKind(trace.SpanKindConsumer),
+					trace.WithAttributes(
+						attribute.String("messaging.system", "kafka"),
+						attribute.String("messaging.destination.name", msg.Topic),
+						attribute.Int("messaging.kafka.partition", msg.Partition),
+						attribute.Int64("messaging.kafka.offset", msg.Offset),
+					))
+				msgCtx = logger.With(msgCtx, logger.KeyTopic, msg.Topic, logger.KeyPartition, msg.Partition, logger.KeyOffset, msg.Offset)
+				if traceID := tracing.TraceID(msgCtx); traceID != "" {
+					msgCtx = logger.With(msgCtx, logger.KeyTraceID, traceID)
+				}
+				metrics.MessagesConsumed.WithLabelValues(c.topic.Name).Inc()
+				metrics.ObserveLag(msg.Topic, msg.Partition, msg.Offset, msg.HighWaterMark)
+				start := time.Now()
+				err = handle(msgCtx, msg)
+				metrics.ProcessingDuration.WithLabelValues(c.topic.Name).Observe(time.Since(start).Seconds())
+				tracing.RecordError(span, err)
+				span.End()
+				if err != nil {
+					metrics.MessagesFailed.WithLabelValues(c.topic.Name, failureStage(err)).Inc()
+					logger.FromContext(msgCtx).Error("failed to process message", "stage", failureStage(err), "error", err)
+					continue
+				}
+				metrics.MessagesProcessed.WithLabelValues(c.topic.Name).Inc()
+			}
+		}(reader)
+	}
+	wg.Wait()
+}
+
+// ошибка обработки сообщения с этапом, на котором она произошла
+type StageError struct {
+	Stage string
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+func atStage(stage string, err error) error {
+	return &StageError{Stage: stage, Err: err}
+}
+
+// этап обработки из ошибки; handle, если обработчик его не указал
+func failureStage(err error) string {
+	var stageErr *StageError
+	if errors.As(err, &stageErr) {
+		return stageErr.Stage
+	}
+	return metrics.StageHandle
+}
+
+// закрывает всех читателей и писателя DLQ, даже если часть из них закрылась с ошибкой
 func (c *Consumer) Close() error {
-	if err := c.reader.Close(); err != nil {
-		return fmt.Errorf("failed to close reader: %w", err)
+	var errs []error
+	for _, reader := range c.readers {
+		if err := reader.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close reader: %w", err))
+		}
 	}
-	return nil
+	if c.deadLetter != nil {
+		if err := c.deadLetter.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close dead letter writer: %w", err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
-func (c *Consumer) ProcessMessage(ctx context.Context, msg kafka.Message) error {
-	var order models.Order
-	err := json.Unmarshal(msg.Value, &order)
+// сохраняет заказ из сообщения и возвращает его в том виде, в каком он записан в БД
+func (c *Consumer) ProcessMessage(ctx context.Context, msg kafka.Message) (*models.Order, error) {
+	ctx = withMessageAudit(ctx, msg)
+	_, span := tracing.Start(ctx, "order.decode")
+	order, err := c.decoder.Decode(msg)
+	tracing.RecordError(span, err)
+	span.End()
+	if errors.Is(err, ErrUnsupportedSchemaVersion) {
+		if dlqErr := c.sendToDeadLetter(ctx, msg, err); dlqErr != nil {
+			metrics.MessagesFailed.WithLabelValues(c.topic.Name, metrics.StageDeadLetter).Inc()
+			logger.FromContext(ctx).Error("failed to send message to dead letter topic", "error", dlqErr)
+		}
+		return nil, atStage(metrics.StageDecode, err)
+	}
+	if err != nil {
+		return nil, atStage(metrics.StageDecode, err)
+	}
+	ctx = logger.With(ctx, logger.KeyOrderUID, order.OrderUID)
+	logger.FromContext(ctx).Info("received order")
+	_, span = tracing.Start(ctx, "order.validate")
+	warnings, err := ValidateOrder(order, c.topic.Consistency)
+	tracing.RecordError(span, err)
+	span.End()
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal message: %w", err)
+		return nil, atStage(metrics.StageValidate, fmt.Errorf("failed to validate order: %w", err))
 	}
-	log.Printf("Received order: %s", order.OrderUID)
-	if err = ValidData(&order); err != nil {
-		return fmt.Errorf("failed to validate order: %w", err)
+	if len(warnings) > 0 {
+		logger.FromContext(ctx).Warn("order is inconsistent", "warnings", warnings)
 	}
 
-	err = c.db.CreateOrder(ctx, &order)
+	err = c.db.CreateOrder(ctx, order)
 	if err != nil {
-		return fmt.Errorf("failed to create order in database: %w", err)
+		return nil, atStage(metrics.StageStore, fmt.Errorf("failed to create order in database: %w", err))
 	}
 
-	log.Printf("Order %s processed successfully", order.OrderUID)
+	logger.FromContext(ctx).Info("order processed")
+	return order, nil
+}
+
+// применяет событие смены статуса заказа и возвращает uid измененного заказа
+func (c *Consumer) ProcessStatusMessage(ctx context.Context, msg kafka.Message) (string, error) {
+	ctx = withMessageAudit(ctx, msg)
+	var update models.StatusUpdate
+	err := json.Unmarshal(msg.Value, &update)
+	if err != nil {
+		return "", atStage(metrics.StageDecode, fmt.Errorf("failed to unmarshal status update: %w", err))
+	}
+	ctx = logger.With(ctx, logger.KeyOrderUID, update.OrderUID)
+	logger.FromContext(ctx).Info("received status update", "status", update.Status)
+	if err = ValidStatusUpdate(&update); err != nil {
+		return "", atStage(metrics.StageValidate, fmt.Errorf("failed to validate status update: %w", err))
+	}
+
+	err = c.db.UpdateOrderStatus(ctx, update.OrderUID, update.Status, update.Reason, 0)
+	if err != nil {
+		return "", atStage(metrics.StageStore, fmt.Errorf("failed to update order status: %w", err))
+	}
+
+	logger.FromContext(ctx).Info("order status changed", "status", update.Status)
+	return update.OrderUID, nil
+}
+
+// отменяет заказ и возвращает его uid
+func (c *Consumer) ProcessCancellation(ctx context.Context, msg kafka.Message) (string, error) {
+	ctx = withMessageAudit(ctx, msg)
+	var cancellation models.Cancellation
+	err := json.Unmarshal(msg.Value, &cancellation)
+	if err != nil {
+		return "", atStage(metrics.StageDecode, fmt.Errorf("failed to unmarshal cancellation: %w", err))
+	}
+	ctx = logger.With(ctx, logger.KeyOrderUID, cancellation.OrderUID)
+	logger.FromContext(ctx).Info("received cancellation")
+	if err = ValidCancellation(&cancellation); err != nil {
+		return "", atStage(metrics.StageValidate, fmt.Errorf("failed to validate cancellation: %w", err))
+	}
+
+	err = c.db.UpdateOrderStatus(ctx, cancellation.OrderUID, models.StatusCancelled, cancellation.Reason, 0)
+	if err != nil {
+		return "", atStage(metrics.StageStore, fmt.Errorf("failed to cancel order: %w", err))
+	}
+
+	logger.FromContext(ctx).Info("order cancelled")
+	return cancellation.OrderUID, nil
+}
+
+// сверяет подтверждение оплаты с платежом заказа, переводит заказ в paid и возвращает его uid
+func (c *Consumer) ProcessPaymentConfirmation(ctx context.Context, msg kafka.Message) (string, error) {
+	ctx = withMessageAudit(ctx, msg)
+	var confirmation models.PaymentConfirmation
+	err := json.Unmarshal(msg.Value, &confirmation)
+	if err != nil {
+		return "", atStage(metrics.StageDecode, fmt.Errorf("failed to unmarshal payment confirmation: %w", err))
+	}
+	ctx = logger.With(ctx, logger.KeyOrderUID, confirmation.OrderUID)
+	logger.FromContext(ctx).Info("received payment confirmation", "transaction", confirmation.Transaction)
+	if err = ValidPaymentConfirmation(&confirmation); err != nil {
+		return "", atStage(metrics.StageValidate, fmt.Errorf("failed to validate payment confirmation: %w", err))
+	}
+
+	order, err := c.db.GetOrder(ctx, confirmation.OrderUID)
+	if err != nil {
+		return "", atStage(metrics.StageStore, fmt.Errorf("failed to get order: %w", err))
+	}
+	if order.Payment.TransactionNumber != confirmation.Transaction {
+		return "", atStage(metrics.StageValidate, fmt.Errorf("payment transaction %s does not match order %s", confirmation.Transaction, confirmation.OrderUID))
+	}
+	if order.Payment.Amount != confirmation.Amount {
+		return "", atStage(metrics.StageValidate, fmt.Errorf("payment amount %d does not match order amount %d", confirmation.Amount, order.Payment.Amount))
+	}
+
+	reason := "payment confirmed: " + confirmation.Transaction
+	err = c.db.UpdateOrderStatus(ctx, confirmation.OrderUID, models.StatusPaid, reason, order.Version)
+	if err != nil {
+		return "", atStage(metrics.StageStore, fmt.Errorf("failed to update order status: %w", err))
+	}
+
+	logger.FromContext(ctx).Info("order paid")
+	return confirmation.OrderUID, nil
+}
+
+// заголовки, которыми сообщение дополняется при отправке в dead letter топик
+const (
+	HeaderDLQReason    = "dlq-reason"
+	HeaderDLQTopic     = "dlq-source-topic"
+	HeaderDLQPartition = "dlq-source-partition"
+	HeaderDLQOffset    = "dlq-source-offset"
+)
+
+// перекладывает сообщение без изменений в dead letter топик с указанием причины;
+// без настроенного топика сообщение только логируется
+func (c *Consumer) sendToDeadLetter(ctx context.Context, msg kafka.Message, reason error) error {
+	if c.deadLetter == nil {
+		logger.FromContext(ctx).Warn("dead letter topic is not configured, dropping message", "reason", reason)
+		return nil
+	}
+	headers := append([]kafka.Header{}, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: HeaderDLQReason, Value: []byte(reason.Error())},
+		kafka.Header{Key: HeaderDLQTopic, Value: []byte(msg.Topic)},
+		kafka.Header{Key: HeaderDLQPartition, Value: []byte(strconv.Itoa(msg.Partition))},
+		kafka.Header{Key: HeaderDLQOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+	)
+	err := c.deadLetter.WriteMessages(ctx, kafka.Message{Key: msg.Key, Value: msg.Value, Headers: headers})
+	if err != nil {
+		return fmt.Errorf("failed to write dead letter message: %w", err)
+	}
+	logger.FromContext(ctx).Warn("message sent to dead letter topic", "dead_letter_topic", c.deadLetter.Topic, "reason", reason)
 	return nil
 }
+
+// источник изменения для журнала - координаты сообщения в Kafka
+func withMessageAudit(ctx context.Context, msg kafka.Message) context.Context {
+	return db.WithAudit(ctx, db.AuditInfo{
+		Actor:  "kafka-consumer",
+		Source: fmt.Sprintf("kafka:%s/%d/%d", msg.Topic, msg.Partition, msg.Offset),
+	})
+}
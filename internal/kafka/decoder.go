@@ -0,0 +1,169 @@
+package kafka
+
+import (
+	"L0WB/internal/models"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"strconv"
+	"strings"
+)
+
+// форматы полезной нагрузки сообщений
+const (
+	FormatJSON     = "json"
+	FormatProtobuf = "protobuf"
+	FormatAvro     = "avro"
+)
+
+// заголовки сообщения, влияющие на декодирование
+const (
+	HeaderContentType = "content-type"
+	HeaderSchemaID    = "schema-id"
+)
+
+// магический байт формата Confluent: 0x00 + 4 байта ID схемы
+const wireMagicByte = 0
+
+// превращает полезную нагрузку сообщения в заказ
+type Decoder interface {
+	Decode(msg kafka.Message) (*models.Order, error)
+}
+
+// формат из content-type сообщения; пусто - заголовка нет или он не распознан
+func formatFromHeaders(msg kafka.Message) string {
+	ct := strings.ToLower(headerValue(msg, HeaderContentType))
+	switch {
+	case ct == "":
+		return ""
+	case strings.Contains(ct, "json"):
+		return FormatJSON
+	case strings.Contains(ct, "protobuf"):
+		return FormatProtobuf
+	case strings.Contains(ct, "avro"):
+		return FormatAvro
+	}
+	return ""
+}
+
+func headerValue(msg kafka.Message, key string) string {
+	for _, h := range msg.Headers {
+		if strings.EqualFold(h.Key, key) {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// определяет схему сообщения. ID берется из заголовка schema-id, а без него - из
+// заголовка формата Confluent (0x00 + 4 байта ID), но только если такой ID есть в реестре:
+// с нулевого байта может начинаться и обычная Avro-запись, тогда она разбирается целиком.
+// framed - заголовок Confluent отделен от payload; rs == nil - ID в сообщении нет
+func resolveSchema(registry *SchemaRegistry, msg kafka.Message) (rs *RegisteredSchema, payload []byte, framed bool, err error) {
+	v := msg.Value
+	if h := headerValue(msg, HeaderSchemaID); h != "" {
+		id, err := strconv.Atoi(h)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("invalid %s header: %s", HeaderSchemaID, h)
+		}
+		rs, err = registry.ByID(id)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		return rs, v, false, nil
+	}
+	if len(v) >= 5 && v[0] == wireMagicByte {
+		if rs, err = registry.ByID(int(binary.BigEndian.Uint32(v[1:5]))); err == nil {
+			return rs, v[5:], true, nil
+		}
+	}
+	return nil, v, false, nil
+}
+
+// разбирает JSON, при необходимости предварительно проверяя его по JSON Schema
+type JSONDecoder struct {
+	SchemaValidation bool
+}
+
+func (d JSONDecoder) Decode(msg kafka.Message) (*models.Order, error) {
+	raw, err := upcastJSON(msg)
+	if err != nil {
+		return nil, err
+	}
+	if d.SchemaValidation {
+		if err := ValidSchema(raw); err != nil {
+			return nil, fmt.Errorf("message does not match order schema: %w", err)
+		}
+	}
+	var order models.Order
+	if err := json.Unmarshal(raw, &order); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal message: %w", err)
+	}
+	return &order, nil
+}
+
+// определяет версию формата (заголовок, затем поле schema_version, иначе 1)
+// и приводит документ к текущей версии
+func upcastJSON(msg kafka.Message) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(msg.Value))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal message: %w", err)
+	}
+
+	version, explicit, err := headerSchemaVersion(msg)
+	if err != nil {
+		return nil, err
+	}
+	if field, ok := doc[SchemaVersionField]; ok {
+		n, ok := field.(json.Number)
+		fieldVersion, err := n.Int64()
+		if !ok || err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", SchemaVersionField, field)
+		}
+		if !explicit {
+			version, explicit = int(fieldVersion), true
+		}
+		delete(doc, SchemaVersionField)
+	}
+	// явно указанная версия 0 не считается отсутствующей и отклоняется в Upcast
+	if !explicit {
+		version = 1
+	}
+	if version == CurrentSchemaVersion {
+		return json.Marshal(doc)
+	}
+	if err = Upcast(doc, version); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// выбирает декодер по content-type сообщения, а если его нет - по формату топика
+type MultiDecoder struct {
+	Default  string
+	Decoders map[string]Decoder
+}
+
+func (d MultiDecoder) Decode(msg kafka.Message) (*models.Order, error) {
+	format := formatFromHeaders(msg)
+	if format == "" {
+		format = d.Default
+	}
+	dec, ok := d.Decoders[format]
+	if !ok {
+		return nil, fmt.Errorf("no decoder for payload format %q", format)
+	}
+	// бинарные форматы апкастингу не подлежат, но слишком новую версию отклоняем для всех
+	version, explicit, err := headerSchemaVersion(msg)
+	if err != nil {
+		return nil, err
+	}
+	if explicit && (version < 1 || version > CurrentSchemaVersion) {
+		return nil, fmt.Errorf("%w: %d (supported 1..%d)", ErrUnsupportedSchemaVersion, version, CurrentSchemaVersion)
+	}
+	return dec.Decode(msg)
+}
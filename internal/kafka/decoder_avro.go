@@ -0,0 +1,46 @@
+package kafka
+
+import (
+	"L0WB/internal/models"
+	"encoding/json"
+	"fmt"
+	"github.com/hamba/avro/v2"
+	"github.com/segmentio/kafka-go"
+)
+
+// декодирует Avro по схеме из локального реестра
+type AvroDecoder struct {
+	Registry *SchemaRegistry
+	// subject, схема которого берется, если ID в сообщении не указан
+	Subject string
+}
+
+func (d AvroDecoder) Decode(msg kafka.Message) (*models.Order, error) {
+	rs, payload, _, err := resolveSchema(d.Registry, msg)
+	if err != nil {
+		return nil, err
+	}
+	if rs == nil {
+		if rs, err = d.Registry.Latest(d.Subject, FormatAvro); err != nil {
+			return nil, err
+		}
+	}
+	if rs.Format != FormatAvro {
+		return nil, fmt.Errorf("schema %d is %s, not avro", rs.ID, rs.Format)
+	}
+
+	var record map[string]any
+	if err = avro.Unmarshal(rs.avro, payload, &record); err != nil {
+		return nil, fmt.Errorf("failed to decode avro payload: %w", err)
+	}
+	// запись приводится к models.Order через JSON: имена полей схемы совпадают с json-тегами
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert avro record: %w", err)
+	}
+	var order models.Order
+	if err = json.Unmarshal(raw, &order); err != nil {
+		return nil, fmt.Errorf("failed to convert avro record: %w", err)
+	}
+	return &order, nil
+}
@@ -0,0 +1,99 @@
+package kafka
+
+import (
+	"github.com/hamba/avro/v2"
+	"github.com/segmentio/kafka-go"
+	"strings"
+	"testing"
+	"time"
+)
+
+func avroOrder(t *testing.T, r *SchemaRegistry, orderUID string) []byte {
+	t.Helper()
+	rs, err := r.ByID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := map[string]any{
+		"order_uid": orderUID, "track_number": "WBILMTESTTRAC", "entry": "WBIL", "locale": "en",
+		"internal_signature": "", "customer_id": "test", "delivery_service": "meest", "shardkey": "9",
+		"sm_id": int64(99), "date_created": time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC), "oof_shard": "1",
+		"delivery": map[string]any{
+			"name": "Test Testov", "phone": "+79161234567", "zip": "123456", "city": "Moscow",
+			"address": "Ploshad Mira 15", "region": "Russia", "email": "test@gmail.com",
+		},
+		"payment": map[string]any{
+			"transaction": "t1", "request_id": "", "currency": "USD", "provider": "wbpay", "amount": int64(1818),
+			"payment_dt": int64(1637907727), "bank": "alpha", "delivery_cost": int64(1500),
+			"goods_total": int64(317), "custom_fee": int64(1),
+		},
+		"items":  []any{},
+		"status": "",
+	}
+	raw, err := avro.Marshal(rs.avro, record)
+	if err != nil {
+		t.Fatalf("avro.Marshal: %v", err)
+	}
+	return raw
+}
+
+func TestAvroDecoder(t *testing.T) {
+	r := testRegistry(t)
+	// пустая строка кодируется длиной 0, поэтому запись начинается с байта 0x00
+	emptyUID := avroOrder(t, r, "")
+	if emptyUID[0] != wireMagicByte {
+		t.Fatalf("record with empty order_uid starts with %#x", emptyUID[0])
+	}
+
+	tests := []struct {
+		name    string
+		value   []byte
+		headers []kafka.Header
+		wantUID string
+		wantErr string
+	}{
+		{name: "latest schema of subject", value: avroOrder(t, r, "b563feb7b2b84b6test"), wantUID: "b563feb7b2b84b6test"},
+		{name: "confluent frame", value: confluentFrame(1, avroOrder(t, r, "framed")), wantUID: "framed"},
+		{
+			name:    "schema-id header",
+			value:   avroOrder(t, r, "header"),
+			headers: []kafka.Header{{Key: HeaderSchemaID, Value: []byte("1")}},
+			wantUID: "header",
+		},
+		{
+			name:    "schema-id header wins over leading zero",
+			value:   emptyUID,
+			headers: []kafka.Header{{Key: HeaderSchemaID, Value: []byte("1")}},
+		},
+		{name: "leading zero without known schema id", value: emptyUID},
+		{
+			name:    "schema of another format",
+			value:   confluentFrame(2, avroOrder(t, r, "x")),
+			wantErr: "schema 2 is protobuf, not avro",
+		},
+		{
+			name:    "invalid schema-id header",
+			value:   avroOrder(t, r, "x"),
+			headers: []kafka.Header{{Key: HeaderSchemaID, Value: []byte("one")}},
+			wantErr: "invalid schema-id header",
+		},
+	}
+	dec := AvroDecoder{Registry: r, Subject: "orders-value"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := dec.Decode(kafka.Message{Value: tt.value, Headers: tt.headers})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Decode() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if got.OrderUID != tt.wantUID || got.Payment.Amount != 1818 || got.Delivery.City != "Moscow" {
+				t.Errorf("Decode() = %+v", got)
+			}
+		})
+	}
+}
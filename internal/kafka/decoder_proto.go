@@ -0,0 +1,276 @@
+package kafka
+
+import (
+	"L0WB/internal/models"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/encoding/protowire"
+	"time"
+)
+
+// декодирует Protobuf-сообщение по схеме schemas/order.proto.
+// Поля разбираются напрямую по номерам, без сгенерированного кода
+type ProtobufDecoder struct {
+	Registry *SchemaRegistry
+}
+
+func (d ProtobufDecoder) Decode(msg kafka.Message) (*models.Order, error) {
+	rs, payload, framed, err := resolveSchema(d.Registry, msg)
+	if err != nil {
+		return nil, err
+	}
+	if rs != nil && rs.Format != FormatProtobuf {
+		return nil, fmt.Errorf("schema %d is %s, not protobuf", rs.ID, rs.Format)
+	}
+	if framed {
+		payload, err = skipMessageIndexes(payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	order := &models.Order{}
+	if err = decodeProto(payload, orderProtoFields, func(num protowire.Number, v protoValue) error {
+		return setOrderField(order, num, v)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to decode protobuf payload: %w", err)
+	}
+	return order, nil
+}
+
+// в формате Confluent после ID схемы идет массив индексов сообщения в .proto
+func skipMessageIndexes(b []byte) ([]byte, error) {
+	count, n := protowire.ConsumeVarint(b)
+	if n < 0 {
+		return nil, fmt.Errorf("invalid protobuf message indexes")
+	}
+	b = b[n:]
+	for i := 0; i < int(protowire.DecodeZigZag(count)); i++ {
+		_, n = protowire.ConsumeVarint(b)
+		if n < 0 {
+			return nil, fmt.Errorf("invalid protobuf message indexes")
+		}
+		b = b[n:]
+	}
+	return b, nil
+}
+
+// значение поля: varint или байты (строка либо вложенное сообщение)
+type protoValue struct {
+	varint uint64
+	bytes  []byte
+}
+
+func (v protoValue) str() string { return string(v.bytes) }
+func (v protoValue) int() int    { return int(int64(v.varint)) }
+
+// номера полей сообщения и их ожидаемый wire type по schemas/order.proto;
+// совпадение со схемой проверяет TestProtoFieldsMatchSchema
+type protoFields map[protowire.Number]protowire.Type
+
+const (
+	protoBytes  = protowire.BytesType
+	protoVarint = protowire.VarintType
+)
+
+var (
+	orderProtoFields = protoFields{
+		1: protoBytes, 2: protoBytes, 3: protoBytes, 4: protoBytes, 5: protoBytes, 6: protoBytes,
+		7: protoBytes, 8: protoBytes, 9: protoVarint, 10: protoBytes, 11: protoBytes, 12: protoBytes,
+		13: protoBytes, 14: protoBytes, 15: protoBytes,
+	}
+	deliveryProtoFields = protoFields{
+		1: protoBytes, 2: protoBytes, 3: protoBytes, 4: protoBytes, 5: protoBytes, 6: protoBytes, 7: protoBytes,
+	}
+	paymentProtoFields = protoFields{
+		1: protoBytes, 2: protoBytes, 3: protoBytes, 4: protoBytes, 5: protoVarint,
+		6: protoVarint, 7: protoBytes, 8: protoVarint, 9: protoVarint, 10: protoVarint,
+	}
+	itemProtoFields = protoFields{
+		1: protoVarint, 2: protoBytes, 3: protoVarint, 4: protoBytes, 5: protoBytes, 6: protoVarint,
+		7: protoBytes, 8: protoVarint, 9: protoVarint, 10: protoBytes, 11: protoVarint,
+	}
+	timestampProtoFields = protoFields{1: protoVarint, 2: protoVarint}
+)
+
+// разбирает сообщение и вызывает set для полей из fields; поле схемы с другим
+// wire type - ошибка, неизвестные поля пропускаются для совместимости
+func decodeProto(b []byte, fields protoFields, set func(protowire.Number, protoValue) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		expected, known := fields[num]
+		if !known {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		if typ != expected {
+			return fmt.Errorf("field %d: wire type %d, expected %d", num, typ, expected)
+		}
+
+		var v protoValue
+		if typ == protowire.VarintType {
+			v.varint, n = protowire.ConsumeVarint(b)
+		} else {
+			v.bytes, n = protowire.ConsumeBytes(b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if err := set(num, v); err != nil {
+			return fmt.Errorf("field %d: %w", num, err)
+		}
+	}
+	return nil
+}
+
+func setOrderField(o *models.Order, num protowire.Number, v protoValue) error {
+	switch num {
+	case 1:
+		o.OrderUID = v.str()
+	case 2:
+		o.TrackNumber = v.str()
+	case 3:
+		o.Entry = v.str()
+	case 4:
+		o.Locale = v.str()
+	case 5:
+		o.InternalSignature = v.str()
+	case 6:
+		o.CustomerID = v.str()
+	case 7:
+		o.DeliveryService = v.str()
+	case 8:
+		o.Shardkey = v.str()
+	case 9:
+		o.SmID = v.int()
+	case 10:
+		t, err := decodeTimestamp(v.bytes)
+		if err != nil {
+			return err
+		}
+		o.DateCreated = t
+	case 11:
+		o.OofShard = v.str()
+	case 12:
+		return decodeProto(v.bytes, deliveryProtoFields, func(n protowire.Number, fv protoValue) error {
+			setDeliveryField(&o.Delivery, n, fv)
+			return nil
+		})
+	case 13:
+		return decodeProto(v.bytes, paymentProtoFields, func(n protowire.Number, fv protoValue) error {
+			setPaymentField(&o.Payment, n, fv)
+			return nil
+		})
+	case 14:
+		var item models.Item
+		err := decodeProto(v.bytes, itemProtoFields, func(n protowire.Number, fv protoValue) error {
+			setItemField(&item, n, fv)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		o.Items = append(o.Items, item)
+	case 15:
+		o.Status = models.OrderStatus(v.str())
+	}
+	return nil
+}
+
+func setDeliveryField(d *models.Delivery, num protowire.Number, v protoValue) {
+	switch num {
+	case 1:
+		d.Name = v.str()
+	case 2:
+		d.Phone = v.str()
+	case 3:
+		d.Zip = v.str()
+	case 4:
+		d.City = v.str()
+	case 5:
+		d.Address = v.str()
+	case 6:
+		d.Region = v.str()
+	case 7:
+		d.Email = v.str()
+	}
+}
+
+func setPaymentField(p *models.Payment, num protowire.Number, v protoValue) {
+	switch num {
+	case 1:
+		p.TransactionNumber = v.str()
+	case 2:
+		p.RequestID = v.str()
+	case 3:
+		p.Currency = v.str()
+	case 4:
+		p.Provider = v.str()
+	case 5:
+		p.Amount = v.int()
+	case 6:
+		p.PaymentDT = int64(v.varint)
+	case 7:
+		p.Bank = v.str()
+	case 8:
+		p.DeliveryCost = v.int()
+	case 9:
+		p.GoodsTotal = v.int()
+	case 10:
+		p.CustomFee = v.int()
+	}
+}
+
+func setItemField(i *models.Item, num protowire.Number, v protoValue) {
+	switch num {
+	case 1:
+		i.ChrtID = v.int()
+	case 2:
+		i.TrackNumber = v.str()
+	case 3:
+		i.Price = v.int()
+	case 4:
+		i.RID = v.str()
+	case 5:
+		i.ItemName = v.str()
+	case 6:
+		i.Sale = v.int()
+	case 7:
+		i.ItemSize = v.str()
+	case 8:
+		i.TotalPrice = v.int()
+	case 9:
+		i.NmID = v.int()
+	case 10:
+		i.Brand = v.str()
+	case 11:
+		i.Status = v.int()
+	}
+}
+
+// google.protobuf.Timestamp: seconds = 1, nanos = 2
+func decodeTimestamp(b []byte) (time.Time, error) {
+	var seconds, nanos int64
+	err := decodeProto(b, timestampProtoFields, func(n protowire.Number, v protoValue) error {
+		switch n {
+		case 1:
+			seconds = int64(v.varint)
+		case 2:
+			nanos = int64(v.varint)
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(seconds, nanos).UTC(), nil
+}
@@ -0,0 +1,239 @@
+package kafka
+
+import (
+	"L0WB/internal/models"
+	"bufio"
+	"encoding/binary"
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/encoding/protowire"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testRegistry(t *testing.T) *SchemaRegistry {
+	t.Helper()
+	r, err := LoadSchemaRegistry("../../schemas")
+	if err != nil {
+		t.Fatalf("LoadSchemaRegistry: %v", err)
+	}
+	return r
+}
+
+// сообщение в формате Confluent: 0x00, ID схемы и данные
+func confluentFrame(id uint32, payload ...[]byte) []byte {
+	b := []byte{wireMagicByte, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(b[1:], id)
+	for _, p := range payload {
+		b = append(b, p...)
+	}
+	return b
+}
+
+func protoString(b []byte, num protowire.Number, s string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func protoVarintField(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func protoMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+func protoOrder() []byte {
+	var ts []byte
+	ts = protoVarintField(ts, 1, 1637907739)
+	ts = protoVarintField(ts, 2, 5)
+
+	var delivery []byte
+	delivery = protoString(delivery, 1, "Test Testov")
+	delivery = protoString(delivery, 2, "+79161234567")
+
+	var payment []byte
+	payment = protoString(payment, 3, "USD")
+	payment = protoVarintField(payment, 5, 1818)
+	payment = protoVarintField(payment, 6, 1637907727)
+
+	var item []byte
+	item = protoVarintField(item, 1, 9934930)
+	item = protoString(item, 5, "Mascaras")
+
+	var b []byte
+	b = protoString(b, 1, "b563feb7b2b84b6test")
+	b = protoVarintField(b, 9, 99)
+	b = protoMessage(b, 10, ts)
+	b = protoMessage(b, 12, delivery)
+	b = protoMessage(b, 13, payment)
+	b = protoMessage(b, 14, item)
+	b = protoMessage(b, 14, item)
+	b = protoString(b, 15, "paid")
+	return b
+}
+
+func TestProtobufDecoder(t *testing.T) {
+	want := &models.Order{
+		OrderUID:    "b563feb7b2b84b6test",
+		SmID:        99,
+		DateCreated: time.Unix(1637907739, 5).UTC(),
+		Delivery:    models.Delivery{Name: "Test Testov", Phone: "+79161234567"},
+		Payment:     models.Payment{Currency: "USD", Amount: 1818, PaymentDT: 1637907727},
+		Items: []models.Item{
+			{ChrtID: 9934930, ItemName: "Mascaras"},
+			{ChrtID: 9934930, ItemName: "Mascaras"},
+		},
+		Status: models.StatusPaid,
+	}
+	// поле 16 с wire type fixed32 в схеме отсутствует
+	withUnknown := protowire.AppendTag(protoOrder(), 16, protowire.Fixed32Type)
+	withUnknown = protowire.AppendFixed32(withUnknown, 7)
+
+	tests := []struct {
+		name    string
+		value   []byte
+		headers []kafka.Header
+		wantErr string
+	}{
+		{name: "plain payload", value: protoOrder()},
+		{name: "unknown field skipped", value: withUnknown},
+		{name: "confluent frame", value: confluentFrame(2, []byte{0}, protoOrder())},
+		{name: "schema-id header", value: protoOrder(), headers: []kafka.Header{{Key: HeaderSchemaID, Value: []byte("2")}}},
+		{
+			name:    "schema of another format",
+			value:   confluentFrame(1, []byte{0}, protoOrder()),
+			wantErr: "schema 1 is avro, not protobuf",
+		},
+		{
+			name:    "unknown schema in header",
+			value:   protoOrder(),
+			headers: []kafka.Header{{Key: HeaderSchemaID, Value: []byte("42")}},
+			wantErr: "schema 42 not found",
+		},
+		{
+			name:    "unknown schema in frame is not stripped",
+			value:   confluentFrame(42, []byte{0}, protoOrder()),
+			wantErr: "failed to decode protobuf payload",
+		},
+		{
+			name:    "wire type mismatch",
+			value:   protoString(nil, 9, "99"),
+			wantErr: "field 9: wire type 2, expected 0",
+		},
+		{
+			name:    "nested wire type mismatch",
+			value:   protoMessage(nil, 13, protoString(nil, 5, "1818")),
+			wantErr: "field 13: field 5: wire type 2, expected 0",
+		},
+		{
+			name:    "truncated",
+			value:   protoOrder()[:10],
+			wantErr: "failed to decode protobuf payload",
+		},
+	}
+	dec := ProtobufDecoder{Registry: testRegistry(t)}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := dec.Decode(kafka.Message{Value: tt.value, Headers: tt.headers})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Decode() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Decode() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+var (
+	protoMessageRe = regexp.MustCompile(`^message (\w+) \{$`)
+	protoFieldRe   = regexp.MustCompile(`^(?:repeated )?([\w.]+) \w+ = (\d+);$`)
+)
+
+// wire type скалярных типов; остальные типы - вложенные сообщения
+var protoScalarTypes = map[string]protowire.Type{
+	"string": protowire.BytesType, "bytes": protowire.BytesType,
+	"int32": protowire.VarintType, "int64": protowire.VarintType,
+	"uint32": protowire.VarintType, "uint64": protowire.VarintType, "bool": protowire.VarintType,
+}
+
+// разбирает плоские message из .proto: номер поля и wire type его типа
+func parseProtoFile(t *testing.T, path string) map[string]protoFields {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	messages := map[string]protoFields{}
+	var current protoFields
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.Join(strings.Fields(scanner.Text()), " ")
+		switch m := protoMessageRe.FindStringSubmatch(line); {
+		case m != nil:
+			current = protoFields{}
+			messages[m[1]] = current
+			continue
+		case line == "}":
+			current = nil
+			continue
+		}
+		m := protoFieldRe.FindStringSubmatch(line)
+		if current == nil || m == nil {
+			continue
+		}
+		num, _ := strconv.Atoi(m[2])
+		typ, scalar := protoScalarTypes[m[1]]
+		if !scalar {
+			typ = protowire.BytesType
+		}
+		current[protowire.Number(num)] = typ
+	}
+	if err = scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return messages
+}
+
+// таблицы полей в decoder_proto.go написаны вручную и должны совпадать со схемой
+func TestProtoFieldsMatchSchema(t *testing.T) {
+	messages := parseProtoFile(t, "../../schemas/order.proto")
+	tables := map[string]protoFields{
+		"Order":    orderProtoFields,
+		"Delivery": deliveryProtoFields,
+		"Payment":  paymentProtoFields,
+		"Item":     itemProtoFields,
+	}
+	if len(messages) != len(tables) {
+		t.Errorf("order.proto has %d messages, decoder knows %d", len(messages), len(tables))
+	}
+	for name, table := range tables {
+		fields, ok := messages[name]
+		if !ok {
+			t.Errorf("message %s not found in order.proto", name)
+			continue
+		}
+		if !reflect.DeepEqual(table, fields) {
+			t.Errorf("%s fields = %v, order.proto has %v", name, table, fields)
+		}
+	}
+	// google.protobuf.Timestamp: int64 seconds = 1; int32 nanos = 2
+	if want := (protoFields{1: protowire.VarintType, 2: protowire.VarintType}); !reflect.DeepEqual(timestampProtoFields, want) {
+		t.Errorf("Timestamp fields = %v, want %v", timestampProtoFields, want)
+	}
+}
@@ -0,0 +1,83 @@
+package kafka
+
+import (
+	"fmt"
+	"github.com/hamba/avro/v2"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+)
+
+// схема, зарегистрированная в локальном реестре
+type RegisteredSchema struct {
+	ID      int    `yaml:"id"`
+	Subject string `yaml:"subject"`
+	Format  string `yaml:"format"`
+	File    string `yaml:"file"`
+
+	avro avro.Schema
+}
+
+// файловая замена Schema Registry: схемы читаются из каталога по registry.yaml
+type SchemaRegistry struct {
+	schemas map[int]*RegisteredSchema
+	// последняя (с наибольшим ID) схема для пары subject/format
+	latest map[string]*RegisteredSchema
+}
+
+// загружает реестр из dir/registry.yaml и разбирает Avro-схемы
+func LoadSchemaRegistry(dir string) (*SchemaRegistry, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "registry.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema registry: %w", err)
+	}
+	var file struct {
+		Schemas []*RegisteredSchema `yaml:"schemas"`
+	}
+	if err = yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse schema registry: %w", err)
+	}
+
+	r := &SchemaRegistry{schemas: map[int]*RegisteredSchema{}, latest: map[string]*RegisteredSchema{}}
+	for _, s := range file.Schemas {
+		if _, dup := r.schemas[s.ID]; dup {
+			return nil, fmt.Errorf("duplicate schema id %d", s.ID)
+		}
+		path := filepath.Join(dir, s.File)
+		switch s.Format {
+		case FormatAvro:
+			s.avro, err = avro.ParseFiles(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse avro schema %s: %w", path, err)
+			}
+		case FormatProtobuf:
+			if _, err = os.Stat(path); err != nil {
+				return nil, fmt.Errorf("protobuf schema %s: %w", path, err)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported format %q for schema %d", s.Format, s.ID)
+		}
+		r.schemas[s.ID] = s
+		key := s.Subject + "/" + s.Format
+		if cur, ok := r.latest[key]; !ok || s.ID > cur.ID {
+			r.latest[key] = s
+		}
+	}
+	return r, nil
+}
+
+func (r *SchemaRegistry) ByID(id int) (*RegisteredSchema, error) {
+	s, ok := r.schemas[id]
+	if !ok {
+		return nil, fmt.Errorf("schema %d not found in registry", id)
+	}
+	return s, nil
+}
+
+func (r *SchemaRegistry) Latest(subject, format string) (*RegisteredSchema, error) {
+	s, ok := r.latest[subject+"/"+format]
+	if !ok {
+		return nil, fmt.Errorf("no %s schema for subject %s", format, subject)
+	}
+	return s, nil
+}
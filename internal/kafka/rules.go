@@ -0,0 +1,343 @@
+package kafka
+
+import (
+	"L0WB/internal/logger"
+	"L0WB/internal/models"
+	"L0WB/internal/validation"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+//go:embed rules.yaml
+var defaultRulesYAML []byte
+
+// ограничения на одно поле заказа
+type FieldRule struct {
+	Required bool     `yaml:"required"`
+	Len      *int     `yaml:"len"`
+	MinLen   *int     `yaml:"min_len"`
+	MaxLen   *int     `yaml:"max_len"`
+	Regex    string   `yaml:"regex"`
+	Format   string   `yaml:"format"`
+	Enum     []string `yaml:"enum"`
+	Min      *float64 `yaml:"min"`
+	Max      *float64 `yaml:"max"`
+	Message  string   `yaml:"message"`
+
+	re *regexp.Regexp
+}
+
+// проверки формата, доступные в правилах через format
+var formats = map[string]func(value string, parent map[string]any) bool{
+	"phone":    func(v string, _ map[string]any) bool { return validation.ValidPhone(v) },
+	"email":    func(v string, _ map[string]any) bool { return validation.ValidEmail(v) },
+	"currency": func(v string, _ map[string]any) bool { return validation.ValidCurrency(v) },
+	"locale":   func(v string, _ map[string]any) bool { return validation.ValidLocale(v) },
+	// индекс проверяется по шаблону региона из соседнего поля region
+	"postal_code": func(v string, parent map[string]any) bool {
+		region, _ := parent["region"].(string)
+		return validation.ValidPostalCode(region, v)
+	},
+}
+
+// условие выбора профиля; пустое поле подходит под любое значение
+type ProfileMatch struct {
+	Entry  []string `yaml:"entry"`
+	Locale []string `yaml:"locale"`
+}
+
+// переопределение правил для части заказов
+type Profile struct {
+	Name   string                `yaml:"name"`
+	Match  ProfileMatch          `yaml:"match"`
+	Fields map[string]*FieldRule `yaml:"fields"`
+}
+
+// набор правил валидации, загружаемый из YAML
+type RuleSet struct {
+	Fields   map[string]*FieldRule `yaml:"fields"`
+	Profiles []Profile             `yaml:"profiles"`
+}
+
+var activeRules atomic.Pointer[RuleSet]
+
+func init() {
+	rs, err := ParseRules(defaultRulesYAML)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in validation rules: %v", err))
+	}
+	activeRules.Store(rs)
+}
+
+// текущий набор правил, которым пользуется ValidData
+func Rules() *RuleSet {
+	return activeRules.Load()
+}
+
+func SetRules(rs *RuleSet) {
+	activeRules.Store(rs)
+}
+
+// читает и компилирует набор правил из файла
+func LoadRules(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+	return ParseRules(data)
+}
+
+func ParseRules(data []byte) (*RuleSet, error) {
+	rs := &RuleSet{}
+	if err := yaml.Unmarshal(data, rs); err != nil {
+		return nil, fmt.Errorf("failed to parse rules: %w", err)
+	}
+	if err := compileRules(rs.Fields); err != nil {
+		return nil, err
+	}
+	for _, p := range rs.Profiles {
+		if err := compileRules(p.Fields); err != nil {
+			return nil, fmt.Errorf("profile %s: %w", p.Name, err)
+		}
+	}
+	return rs, nil
+}
+
+func compileRules(fields map[string]*FieldRule) error {
+	for path, rule := range fields {
+		if rule == nil {
+			return fmt.Errorf("empty rule for field %s", path)
+		}
+		if _, ok := formats[rule.Format]; rule.Format != "" && !ok {
+			return fmt.Errorf("unknown format %q for field %s", rule.Format, path)
+		}
+		if rule.Regex == "" {
+			continue
+		}
+		re, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex for field %s: %w", path, err)
+		}
+		rule.re = re
+	}
+	return nil
+}
+
+// правила для конкретного заказа с учетом подходящего профиля
+func (rs *RuleSet) fieldsFor(order *models.Order) map[string]*FieldRule {
+	for _, p := range rs.Profiles {
+		if !p.Match.matches(order) {
+			continue
+		}
+		fields := make(map[string]*FieldRule, len(rs.Fields)+len(p.Fields))
+		for path, rule := range rs.Fields {
+			fields[path] = rule
+		}
+		for path, rule := range p.Fields {
+			fields[path] = rule
+		}
+		return fields
+	}
+	return rs.Fields
+}
+
+func (m ProfileMatch) matches(order *models.Order) bool {
+	return matchAny(m.Entry, order.Entry) && matchAny(m.Locale, order.Locale)
+}
+
+func matchAny(values []string, v string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, value := range values {
+		if strings.EqualFold(value, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// проверяет заказ по всем правилам и возвращает все нарушения
+func (rs *RuleSet) Validate(order *models.Order) ValidationErrors {
+	var errs ValidationErrors
+	doc, err := toDocument(order)
+	if err != nil {
+		errs.add("", RuleFormat, nil, "failed to prepare order for validation: %v", err)
+		return errs
+	}
+	for path, rule := range rs.fieldsFor(order) {
+		for _, f := range lookup(doc, strings.Split(path, "."), "") {
+			rule.check(f, &errs)
+		}
+	}
+	sortErrors(errs)
+	return errs
+}
+
+// значение поля заказа по конкретному пути
+type fieldValue struct {
+	path    string
+	value   any
+	present bool
+	parent  map[string]any
+}
+
+func toDocument(order *models.Order) (map[string]any, error) {
+	raw, err := json.Marshal(order)
+	if err != nil {
+		return nil, err
+	}
+	doc := map[string]any{}
+	err = json.Unmarshal(raw, &doc)
+	return doc, err
+}
+
+// раскрывает путь вида items[].rid в значения каждого элемента
+func lookup(node any, segs []string, prefix string) []fieldValue {
+	seg := segs[0]
+	each := strings.HasSuffix(seg, "[]")
+	key := strings.TrimSuffix(seg, "[]")
+	path := joinPath(prefix, key)
+
+	obj, _ := node.(map[string]any)
+	value, ok := obj[key]
+	if !each {
+		if len(segs) == 1 {
+			return []fieldValue{{path: path, value: value, present: ok && value != nil, parent: obj}}
+		}
+		if !ok {
+			return []fieldValue{{path: joinPath(path, strings.Join(segs[1:], "."))}}
+		}
+		return lookup(value, segs[1:], path)
+	}
+
+	arr, _ := value.([]any)
+	var out []fieldValue
+	for i, elem := range arr {
+		elemPath := fmt.Sprintf("%s[%d]", path, i)
+		if len(segs) == 1 {
+			out = append(out, fieldValue{path: elemPath, value: elem, present: elem != nil, parent: obj})
+			continue
+		}
+		out = append(out, lookup(elem, segs[1:], elemPath)...)
+	}
+	return out
+}
+
+func joinPath(prefix, key string) string {
+	switch {
+	case prefix == "":
+		return key
+	case key == "":
+		return prefix
+	}
+	return prefix + "." + key
+}
+
+func (r *FieldRule) check(f fieldValue, errs *ValidationErrors) {
+	fail := func(rule, format string, args ...any) {
+		if r.Message != "" {
+			format, args = "%s", []any{r.Message}
+		}
+		errs.add(f.path, rule, f.value, format, args...)
+	}
+
+	if !f.present || f.value == "" {
+		if r.Required {
+			fail(RuleRequired, "%s is required", lastSegment(f.path))
+			return
+		}
+		if !f.present {
+			return
+		}
+	}
+
+	switch v := f.value.(type) {
+	case string:
+		n := utf8.RuneCountInString(v)
+		switch {
+		case r.Len != nil && n != *r.Len:
+			fail(RuleLength, "%s must be %d characters", lastSegment(f.path), *r.Len)
+		case r.MinLen != nil && n < *r.MinLen:
+			fail(RuleLength, "%s must be at least %d characters", lastSegment(f.path), *r.MinLen)
+		case r.MaxLen != nil && n > *r.MaxLen:
+			fail(RuleLength, "%s must be at most %d characters", lastSegment(f.path), *r.MaxLen)
+		}
+		if r.re != nil && !r.re.MatchString(v) {
+			fail(RuleFormat, "%s must match %s", lastSegment(f.path), r.Regex)
+		}
+		if r.Format != "" && v != "" && !formats[r.Format](v, f.parent) {
+			fail(RuleFormat, "%s must be a valid %s", lastSegment(f.path), r.Format)
+		}
+	case float64:
+		switch {
+		case r.Min != nil && v < *r.Min:
+			fail(RuleMin, "%s must be at least %v", lastSegment(f.path), *r.Min)
+		case r.Max != nil && v > *r.Max:
+			fail(RuleMax, "%s must be at most %v", lastSegment(f.path), *r.Max)
+		}
+	}
+
+	if len(r.Enum) > 0 && !matchEnum(r.Enum, f.value) {
+		fail(RuleEnum, "%s must be one of %s", lastSegment(f.path), strings.Join(r.Enum, ", "))
+	}
+}
+
+func matchEnum(enum []string, value any) bool {
+	s := fmt.Sprint(value)
+	for _, e := range enum {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func lastSegment(path string) string {
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
+
+// перечитывает файл правил при его изменении; при ошибке оставляет прежние правила
+func WatchRules(ctx context.Context, path string, interval time.Duration) {
+	var lastMod time.Time
+	if info, err := os.Stat(path); err == nil {
+		lastMod = info.ModTime()
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(path)
+			if err != nil {
+				logger.FromContext(ctx).Error("failed to stat rules file", "path", path, "error", err)
+				continue
+			}
+			if !info.ModTime().After(lastMod) {
+				continue
+			}
+			lastMod = info.ModTime()
+			rs, err := LoadRules(path)
+			if err != nil {
+				logger.FromContext(ctx).Error("failed to reload validation rules, keeping previous", "path", path, "error", err)
+				continue
+			}
+			SetRules(rs)
+			logger.FromContext(ctx).Info("validation rules reloaded", "path", path)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package kafka
+
+import (
+	"L0WB/internal/models"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRulesErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"invalid yaml", "fields: [", "failed to parse rules"},
+		{"empty rule", "fields:\n  order_uid:\n", "empty rule for field order_uid"},
+		{"unknown format", "fields:\n  order_uid: {format: iban}", `unknown format "iban"`},
+		{"invalid regex", "fields:\n  order_uid: {regex: '('}", "invalid regex for field order_uid"},
+		{"error in profile", "profiles:\n  - name: ru\n    fields:\n      locale: {format: nope}", "profile ru:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRules([]byte(tt.yaml))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseRules() error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleSetValidate(t *testing.T) {
+	const rules = `
+fields:
+  order_uid: {len: 19}
+  locale: {enum: [en, ru]}
+  delivery.city: {min_len: 2, max_len: 6}
+  delivery.zip: {format: postal_code}
+  payment.amount: {min: 1, max: 100}
+  items[].brand: {regex: '^[A-Z]', message: brand must be capitalized}
+  missing.field: {required: true}
+profiles:
+  - name: ru
+    match: {locale: [RU]}
+    fields:
+      delivery.city: {max_len: 10}
+`
+	rs, err := ParseRules([]byte(rules))
+	if err != nil {
+		t.Fatalf("ParseRules: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(o *models.Order)
+		want   []violation
+	}{
+		{
+			name:   "only missing parent path",
+			modify: func(o *models.Order) { o.Payment.Amount = 100 },
+			want:   []violation{{"missing.field", RuleRequired}},
+		},
+		{
+			name: "length bounds",
+			modify: func(o *models.Order) {
+				o.Payment.Amount = 1
+				o.OrderUID = "short"
+				o.Delivery.City = "Moscow1"
+			},
+			want: []violation{
+				{"delivery.city", RuleLength},
+				{"missing.field", RuleRequired},
+				{"order_uid", RuleLength},
+			},
+		},
+		{
+			name: "profile overrides matched field only",
+			modify: func(o *models.Order) {
+				o.Payment.Amount = 1
+				o.Locale = "ru"
+				o.Delivery.City = "Vladivostok"
+			},
+			want: []violation{
+				{"delivery.city", RuleLength},
+				{"missing.field", RuleRequired},
+			},
+		},
+		{
+			name: "enum, max, custom message and format from sibling field",
+			modify: func(o *models.Order) {
+				o.Locale = "de"
+				o.Payment.Amount = 101
+				o.Items[0].Brand = "vivienne"
+				o.Delivery.Zip = "1234"
+			},
+			want: []violation{
+				{"delivery.zip", RuleFormat},
+				{"items[0].brand", RuleFormat},
+				{"locale", RuleEnum},
+				{"missing.field", RuleRequired},
+				{"payment.amount", RuleMax},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := validOrder()
+			tt.modify(order)
+			got := violationsOf(t, rs.Validate(order).Err())
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleCustomMessage(t *testing.T) {
+	rs, err := ParseRules([]byte("fields:\n  entry: {enum: [WBIL], message: unsupported entry}"))
+	if err != nil {
+		t.Fatalf("ParseRules: %v", err)
+	}
+	order := validOrder()
+	order.Entry = "OTHER"
+	errs := rs.Validate(order)
+	if len(errs) != 1 || errs[0].Message != "unsupported entry" || errs[0].Value != "OTHER" {
+		t.Errorf("violations = %+v, want one with custom message and value", errs)
+	}
+}
+
+// при изменении файла правила перечитываются, а некорректный файл не заменяет прежние
+func TestWatchRules(t *testing.T) {
+	prev := Rules()
+	t.Cleanup(func() { SetRules(prev) })
+
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	write := func(content string, mod time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+	write("fields: {}", start)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go WatchRules(ctx, path, 5*time.Millisecond)
+
+	// наблюдатель мог запомнить время изменения уже после записи, поэтому файл
+	// обновляется, пока правила не перечитаются
+	mod := start
+	waitFor(t, func() bool {
+		mod = mod.Add(time.Minute)
+		write("fields:\n  order_uid: {len: 1}", mod)
+		return Rules().Fields["order_uid"] != nil
+	})
+	loaded := Rules()
+
+	write("fields: [", mod.Add(time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	if Rules() != loaded {
+		t.Error("invalid rules file replaced the active rule set")
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
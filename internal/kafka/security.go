@@ -0,0 +1,125 @@
+package kafka
+
+import (
+	"L0WB/internal/config"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+	"os"
+	"strings"
+	"time"
+)
+
+// механизмы SASL
+const (
+	SASLPlain       = "plain"
+	SASLScramSHA256 = "scram-sha-256"
+	SASLScramSHA512 = "scram-sha-512"
+)
+
+// dialer для читателей с учетом TLS и SASL
+func NewDialer(cfg config.KafkaConfig) (*kafka.Dialer, error) {
+	tlsConfig, mechanism, err := security(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &kafka.Dialer{
+		Timeout:       10 * time.Second,
+		DualStack:     true,
+		TLS:           tlsConfig,
+		SASLMechanism: mechanism,
+	}, nil
+}
+
+// транспорт для писателей с учетом TLS и SASL
+func NewTransport(cfg config.KafkaConfig) (*kafka.Transport, error) {
+	tlsConfig, mechanism, err := security(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &kafka.Transport{TLS: tlsConfig, SASL: mechanism}, nil
+}
+
+// писатель в топик с настройками подключения из конфига
+func NewWriter(cfg config.KafkaConfig, topic string) (*kafka.Writer, error) {
+	transport, err := NewTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &kafka.Writer{
+		Addr:      kafka.TCP(cfg.BrokerList()...),
+		Topic:     topic,
+		Balancer:  &kafka.LeastBytes{},
+		Transport: transport,
+	}, nil
+}
+
+func security(cfg config.KafkaConfig) (*tls.Config, sasl.Mechanism, error) {
+	tlsConfig, err := newTLSConfig(cfg.TLS)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to configure kafka tls: %w", err)
+	}
+	mechanism, err := newSASLMechanism(cfg.SASL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to configure kafka sasl: %w", err)
+	}
+	return tlsConfig, mechanism, nil
+}
+
+func newTLSConfig(cfg config.KafkaTLSConfig) (*tls.Config, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.CAFile != "" {
+		ca, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func newSASLMechanism(cfg config.KafkaSASLConfig) (sasl.Mechanism, error) {
+	if cfg.Mechanism == "" {
+		return nil, nil
+	}
+	username, err := cfg.ResolveUsername()
+	if err != nil {
+		return nil, err
+	}
+	password, err := cfg.ResolvePassword()
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(cfg.Mechanism) {
+	case SASLPlain:
+		return plain.Mechanism{Username: username, Password: password}, nil
+	case SASLScramSHA256:
+		return scram.Mechanism(scram.SHA256, username, password)
+	case SASLScramSHA512:
+		return scram.Mechanism(scram.SHA512, username, password)
+	default:
+		return nil, fmt.Errorf("unknown sasl mechanism %q", cfg.Mechanism)
+	}
+}
@@ -0,0 +1,72 @@
+package kafka
+
+import (
+	"L0WB/internal/models"
+	"errors"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"strconv"
+)
+
+// текущая версия формата сообщения с заказом:
+// 1 - исходный формат, 2 - добавлен статус заказа
+const CurrentSchemaVersion = 2
+
+// поле JSON и заголовок сообщения с версией формата
+const (
+	SchemaVersionField  = "schema_version"
+	HeaderSchemaVersion = "schema-version"
+)
+
+var ErrUnsupportedSchemaVersion = errors.New("unsupported schema version")
+
+// переводит JSON-документ заказа из версии N в версию N+1
+type Upcaster func(doc map[string]any) error
+
+// апкастеры по исходной версии
+var upcasters = map[int]Upcaster{
+	1: upcastV1ToV2,
+}
+
+// регистрирует апкастер из версии from в from+1
+func RegisterUpcaster(from int, u Upcaster) {
+	upcasters[from] = u
+}
+
+// последовательно приводит документ версии version к текущей версии
+func Upcast(doc map[string]any, version int) error {
+	if version < 1 || version > CurrentSchemaVersion {
+		return fmt.Errorf("%w: %d (supported 1..%d)", ErrUnsupportedSchemaVersion, version, CurrentSchemaVersion)
+	}
+	for v := version; v < CurrentSchemaVersion; v++ {
+		u, ok := upcasters[v]
+		if !ok {
+			return fmt.Errorf("no upcaster from schema version %d", v)
+		}
+		if err := u(doc); err != nil {
+			return fmt.Errorf("failed to upcast from schema version %d: %w", v, err)
+		}
+	}
+	return nil
+}
+
+// версия из заголовка schema-version; ok = false, если заголовка нет
+func headerSchemaVersion(msg kafka.Message) (version int, ok bool, err error) {
+	h := headerValue(msg, HeaderSchemaVersion)
+	if h == "" {
+		return 0, false, nil
+	}
+	version, err = strconv.Atoi(h)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid %s header: %s", HeaderSchemaVersion, h)
+	}
+	return version, true, nil
+}
+
+// в версии 1 не было статуса: такие заказы создаются в статусе created
+func upcastV1ToV2(doc map[string]any) error {
+	if _, ok := doc["status"]; !ok {
+		doc["status"] = string(models.StatusCreated)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package kafka
+
+import (
+	"L0WB/internal/models"
+	"errors"
+	"github.com/segmentio/kafka-go"
+	"strings"
+	"testing"
+)
+
+func TestUpcast(t *testing.T) {
+	tests := []struct {
+		version    int
+		doc        map[string]any
+		wantStatus any
+		wantErr    error
+	}{
+		{version: 0, doc: map[string]any{}, wantErr: ErrUnsupportedSchemaVersion},
+		{version: 1, doc: map[string]any{}, wantStatus: string(models.StatusCreated)},
+		{version: 1, doc: map[string]any{"status": "paid"}, wantStatus: "paid"},
+		{version: CurrentSchemaVersion, doc: map[string]any{}, wantStatus: nil},
+		{version: CurrentSchemaVersion + 1, doc: map[string]any{}, wantErr: ErrUnsupportedSchemaVersion},
+	}
+	for _, tt := range tests {
+		err := Upcast(tt.doc, tt.version)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("Upcast(v%d) error = %v, want %v", tt.version, err, tt.wantErr)
+			continue
+		}
+		if err == nil && tt.doc["status"] != tt.wantStatus {
+			t.Errorf("Upcast(v%d) status = %v, want %v", tt.version, tt.doc["status"], tt.wantStatus)
+		}
+	}
+}
+
+func TestJSONDecoderVersions(t *testing.T) {
+	versionHeader := func(v string) []kafka.Header {
+		return []kafka.Header{{Key: HeaderSchemaVersion, Value: []byte(v)}}
+	}
+	tests := []struct {
+		name       string
+		value      string
+		headers    []kafka.Header
+		wantStatus models.OrderStatus
+		wantErr    string
+	}{
+		{name: "no version is v1", value: `{"order_uid":"a"}`, wantStatus: models.StatusCreated},
+		{name: "field v1", value: `{"order_uid":"a","schema_version":1}`, wantStatus: models.StatusCreated},
+		{name: "field current", value: `{"order_uid":"a","schema_version":2}`, wantStatus: ""},
+		{name: "header current", value: `{"order_uid":"a"}`, headers: versionHeader("2"), wantStatus: ""},
+		{
+			name:       "header wins over field",
+			value:      `{"order_uid":"a","schema_version":2}`,
+			headers:    versionHeader("1"),
+			wantStatus: models.StatusCreated,
+		},
+		{name: "v1 keeps status", value: `{"order_uid":"a","status":"paid"}`, wantStatus: models.StatusPaid},
+		{name: "newer field", value: `{"order_uid":"a","schema_version":3}`, wantErr: ErrUnsupportedSchemaVersion.Error()},
+		{name: "newer header", value: `{"order_uid":"a"}`, headers: versionHeader("3"), wantErr: ErrUnsupportedSchemaVersion.Error()},
+		{name: "zero field", value: `{"order_uid":"a","schema_version":0}`, wantErr: ErrUnsupportedSchemaVersion.Error()},
+		{name: "zero header", value: `{"order_uid":"a"}`, headers: versionHeader("0"), wantErr: ErrUnsupportedSchemaVersion.Error()},
+		{name: "string field", value: `{"order_uid":"a","schema_version":"2"}`, wantErr: "invalid schema_version"},
+		{name: "fractional field", value: `{"order_uid":"a","schema_version":1.5}`, wantErr: "invalid schema_version"},
+		{name: "invalid header", value: `{"order_uid":"a"}`, headers: versionHeader("v2"), wantErr: "invalid schema-version header"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := JSONDecoder{}.Decode(kafka.Message{Value: []byte(tt.value), Headers: tt.headers})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Decode() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if order.OrderUID != "a" || order.Status != tt.wantStatus {
+				t.Errorf("Decode() = uid %q, status %q; want status %q", order.OrderUID, order.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// более новая и нулевая версии отклоняются и для бинарных форматов, у которых апкастинга нет
+func TestMultiDecoderRejectsUnsupportedVersion(t *testing.T) {
+	dec := MultiDecoder{Default: FormatProtobuf, Decoders: map[string]Decoder{
+		FormatProtobuf: ProtobufDecoder{Registry: testRegistry(t)},
+	}}
+	msg := kafka.Message{
+		Value:   protoOrder(),
+		Headers: []kafka.Header{{Key: HeaderSchemaVersion, Value: []byte("3")}},
+	}
+	if _, err := dec.Decode(msg); !errors.Is(err, ErrUnsupportedSchemaVersion) {
+		t.Errorf("Decode() error = %v, want %v", err, ErrUnsupportedSchemaVersion)
+	}
+	msg.Headers[0].Value = []byte("0")
+	if _, err := dec.Decode(msg); !errors.Is(err, ErrUnsupportedSchemaVersion) {
+		t.Errorf("Decode() of version 0: error = %v, want %v", err, ErrUnsupportedSchemaVersion)
+	}
+	msg.Headers[0].Value = []byte("2")
+	if _, err := dec.Decode(msg); err != nil {
+		t.Errorf("Decode() of current version: %v", err)
+	}
+}
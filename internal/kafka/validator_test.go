@@ -0,0 +1,161 @@
+package kafka
+
+import (
+	"L0WB/internal/models"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// заказ, проходящий правила по умолчанию и проверки согласованности
+func validOrder() *models.Order {
+	return &models.Order{
+		OrderUID:        "b563feb7b2b84b6test",
+		TrackNumber:     "WBILMTESTTRAC",
+		Entry:           "WBIL",
+		Locale:          "en",
+		CustomerID:      "test",
+		DeliveryService: "meest",
+		Shardkey:        "9",
+		SmID:            99,
+		DateCreated:     time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC),
+		OofShard:        "1",
+		Delivery: models.Delivery{
+			Name:    "Test Testov",
+			Phone:   "+79161234567",
+			Zip:     "123456",
+			City:    "Moscow",
+			Address: "Ploshad Mira 15",
+			Region:  "Russia",
+			Email:   "test@gmail.com",
+		},
+		Payment: models.Payment{
+			TransactionNumber: "b563feb7b2b84b6test",
+			Currency:          "USD",
+			Provider:          "wbpay",
+			Amount:            1818,
+			PaymentDT:         1637907727,
+			Bank:              "alpha",
+			DeliveryCost:      1500,
+			GoodsTotal:        317,
+			CustomFee:         1,
+		},
+		Items: []models.Item{{
+			ChrtID:      9934930,
+			TrackNumber: "WBILMTESTTRAC",
+			Price:       453,
+			RID:         "ab4219087a764ae0bte",
+			ItemName:    "Mascaras",
+			Sale:        30,
+			ItemSize:    "0",
+			TotalPrice:  317,
+			NmID:        2389212,
+			Brand:       "Vivienne Sabo",
+			Status:      200,
+		}},
+	}
+}
+
+// путь и правило нарушения без сообщения и значения
+type violation struct {
+	Path string
+	Rule string
+}
+
+func violationsOf(t *testing.T, err error) []violation {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("error %v is not ValidationErrors", err)
+	}
+	out := make([]violation, len(errs))
+	for i, e := range errs {
+		out[i] = violation{e.Path, e.Rule}
+	}
+	return out
+}
+
+func TestValidData(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(o *models.Order)
+		want   []violation
+	}{
+		{
+			name:   "valid",
+			modify: func(o *models.Order) {},
+		},
+		{
+			name:   "missing top-level field",
+			modify: func(o *models.Order) { o.OrderUID = "" },
+			want:   []violation{{"order_uid", RuleRequired}},
+		},
+		{
+			name:   "nested field",
+			modify: func(o *models.Order) { o.Payment.Bank = "" },
+			want:   []violation{{"payment.bank", RuleRequired}},
+		},
+		{
+			name: "item index in path",
+			modify: func(o *models.Order) {
+				o.Items = append(o.Items, o.Items[0])
+				o.Items[1].RID = "short"
+			},
+			want: []violation{{"items[1].rid", RuleLength}},
+		},
+		{
+			name: "all violations sorted by path",
+			modify: func(o *models.Order) {
+				o.Items[0].Sale = 101
+				o.Delivery.Name = ""
+				o.Payment.Amount = 0
+				o.Entry = ""
+			},
+			want: []violation{
+				{"delivery.name", RuleRequired},
+				{"entry", RuleRequired},
+				{"items[0].sale", RuleMax},
+				{"payment.amount", RuleMin},
+			},
+		},
+		{
+			name:   "boundary values pass",
+			modify: func(o *models.Order) { o.Items[0].Sale = 100; o.SmID = 0 },
+		},
+		{
+			name:   "enum",
+			modify: func(o *models.Order) { o.Items[0].Status = 202 },
+			want:   []violation{{"items[0].status", RuleEnum}},
+		},
+		{
+			name:   "unknown status",
+			modify: func(o *models.Order) { o.Status = "lost" },
+			want:   []violation{{"status", RuleEnum}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := validOrder()
+			tt.modify(order)
+			got := violationsOf(t, ValidData(order))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationErrorsErr(t *testing.T) {
+	var empty ValidationErrors
+	if err := empty.Err(); err != nil {
+		t.Errorf("Err() of empty = %v, want nil", err)
+	}
+	errs := ValidationErrors{{Path: "a", Message: "x"}, {Path: "b", Message: "y"}}
+	if got, want := errs.Err().Error(), "2 validation error(s): a: x; b: y"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
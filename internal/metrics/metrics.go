@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"strconv"
+	"time"
+)
+
+const namespace = "order_service"
+
+// этапы обработки сообщения, на которых оно может упасть
+const (
+	StageRead       = "read"
+	StageDecode     = "decode"
+	StageValidate   = "validate"
+	StageStore      = "store"
+	StageDeadLetter = "dead_letter"
+	StageHandle     = "handle"
+)
+
+var (
+	MessagesConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "messages_consumed_total",
+		Help:      "Messages read from Kafka.",
+	}, []string{"topic"})
+
+	MessagesProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "messages_processed_total",
+		Help:      "Messages processed successfully.",
+	}, []string{"topic"})
+
+	MessagesFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "messages_failed_total",
+		Help:      "Messages that failed, by processing stage.",
+	}, []string{"topic", "stage"})
+
+	ProcessingDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "message_processing_seconds",
+		Help:      "Time spent processing a message.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"topic"})
+
+	ConsumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "kafka",
+		Name:      "consumer_lag",
+		Help:      "Messages behind the partition high water mark.",
+	}, []string{"topic", "partition"})
+
+	// время всего вызова хранилища, а не отдельного SQL-запроса: имя метрики осталось
+	// прежним, чтобы не ломать дашборды
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_seconds",
+		Help:      "Latency of a whole storage operation (one observation per call, including its transaction and all of its statements), not of individual SQL statements.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	CacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "hits_total",
+		Help:      "Cache lookups that found the order.",
+	})
+
+	CacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "misses_total",
+		Help:      "Cache lookups that did not find the order.",
+	})
+
+	CacheEvictions = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "evictions_total",
+		Help:      "Orders evicted from the cache.",
+	})
+
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by route, method and status.",
+	}, []string{"route", "method", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_seconds",
+		Help:      "HTTP request latency by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+)
+
+// записывает длительность операции хранилища целиком (транзакция со всеми запросами);
+// использовать как defer ObserveQuery("...", time.Now())
+func ObserveQuery(operation string, start time.Time) {
+	DBQueryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// отставание консьюмера по партиции: сообщений после текущего до конца партиции
+func ObserveLag(topic string, partition int, offset, highWaterMark int64) {
+	lag := highWaterMark - offset - 1
+	if lag < 0 {
+		lag = 0
+	}
+	ConsumerLag.WithLabelValues(topic, strconv.Itoa(partition)).Set(float64(lag))
+}
+
+// состояние пула соединений БД
+type PoolStats struct {
+	Open      int
+	InUse     int
+	Idle      int
+	WaitCount int64
+}
+
+// регистрирует метрики пула соединений БД; stats вызывается при каждом сборе метрик
+func RegisterDBPool(stats func() PoolStats) {
+	gauge := func(name, help string, value func(PoolStats) int) {
+		promauto.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      name,
+			Help:      help,
+		}, func() float64 { return float64(value(stats())) })
+	}
+	gauge("open_connections", "Open database connections.", func(s PoolStats) int { return s.Open })
+	gauge("in_use_connections", "Database connections in use.", func(s PoolStats) int { return s.InUse })
+	gauge("idle_connections", "Idle database connections.", func(s PoolStats) int { return s.Idle })
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "wait_count_total",
+		Help:      "Times a query waited for a free database connection.",
+	}, func() float64 { return float64(stats().WaitCount) })
+}
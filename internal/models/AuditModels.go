@@ -0,0 +1,26 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	AuditStatus AuditAction = "status"
+	AuditDelete AuditAction = "delete"
+)
+
+// запись журнала изменений заказа
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	OrderUID  string          `json:"order_uid"`
+	Action    AuditAction     `json:"action"`
+	Actor     string          `json:"actor"`
+	Source    string          `json:"source"`
+	Diff      json.RawMessage `json:"diff"`
+	CreatedAt time.Time       `json:"created_at"`
+}
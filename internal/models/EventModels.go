@@ -0,0 +1,15 @@
+package models
+
+// событие отмены заказа
+type Cancellation struct {
+	OrderUID string `json:"order_uid"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// подтверждение оплаты заказа от платежного провайдера
+type PaymentConfirmation struct {
+	OrderUID    string `json:"order_uid"`
+	Transaction string `json:"transaction"`
+	Amount      int    `json:"amount"`
+	PaymentDt   int64  `json:"payment_dt,omitempty"`
+}
@@ -0,0 +1,54 @@
+package models
+
+type OrderStatus string
+
+const (
+	StatusCreated   OrderStatus = "created"
+	StatusPaid      OrderStatus = "paid"
+	StatusAssembled OrderStatus = "assembled"
+	StatusShipped   OrderStatus = "shipped"
+	StatusDelivered OrderStatus = "delivered"
+	StatusCancelled OrderStatus = "cancelled"
+	StatusReturned  OrderStatus = "returned"
+)
+
+// допустимые переходы между статусами заказа
+var statusTransitions = map[OrderStatus][]OrderStatus{
+	StatusCreated:   {StatusPaid, StatusCancelled},
+	StatusPaid:      {StatusAssembled, StatusCancelled},
+	StatusAssembled: {StatusShipped, StatusCancelled},
+	StatusShipped:   {StatusDelivered, StatusReturned},
+	StatusDelivered: {StatusReturned},
+	StatusCancelled: {},
+	StatusReturned:  {},
+}
+
+// событие смены статуса заказа (тело PATCH-запроса и сообщение в Kafka)
+type StatusUpdate struct {
+	OrderUID string      `json:"order_uid"`
+	Status   OrderStatus `json:"status"`
+	Reason   string      `json:"reason,omitempty"`
+}
+
+func (s OrderStatus) Valid() bool {
+	_, ok := statusTransitions[s]
+	return ok
+}
+
+// проверяет, можно ли перевести заказ из статуса s в статус to
+func (s OrderStatus) CanTransitionTo(to OrderStatus) bool {
+	for _, next := range statusTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// все статусы заказа, используются в JSON Schema
+func (OrderStatus) JSONSchemaEnum() []any {
+	return []any{
+		StatusCreated, StatusPaid, StatusAssembled, StatusShipped,
+		StatusDelivered, StatusCancelled, StatusReturned,
+	}
+}
@@ -0,0 +1,69 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// экспоненциальная задержка между попытками с общим ограничением по времени
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+	// общее время на все попытки; 0 - пока не отменен ctx
+	Deadline time.Duration
+	// число попыток; 0 - без ограничения
+	MaxAttempts int
+}
+
+const (
+	defaultInitial = 500 * time.Millisecond
+	defaultMax     = 10 * time.Second
+)
+
+// вызывается после каждой неудачной попытки перед ожиданием
+type OnError func(attempt int, err error, wait time.Duration)
+
+// повторяет fn, пока она не вернет nil, не кончатся попытки, не истечет Deadline
+// или не отменится ctx;
+// задержка удваивается от Initial до Max, к ней добавляется случайный разброс до 20%
+func Do(ctx context.Context, b Backoff, fn func(ctx context.Context) error, onError OnError) error {
+	if b.Initial <= 0 {
+		b.Initial = defaultInitial
+	}
+	if b.Max <= 0 {
+		b.Max = defaultMax
+	}
+	if b.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.Deadline)
+		defer cancel()
+	}
+
+	wait := b.Initial
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil || attempt == b.MaxAttempts {
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		}
+		delay := wait + time.Duration(rand.Int63n(int64(wait)/5+1))
+		if onError != nil {
+			onError(attempt, err, delay)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		case <-timer.C:
+		}
+		wait *= 2
+		if wait > b.Max {
+			wait = b.Max
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var errFail = errors.New("fail")
+
+// падает, пока не будет сделано succeedOn попыток; 0 - падает всегда
+func failing(succeedOn int, calls *int) func(context.Context) error {
+	return func(context.Context) error {
+		*calls++
+		if succeedOn > 0 && *calls >= succeedOn {
+			return nil
+		}
+		return errFail
+	}
+}
+
+func TestDo(t *testing.T) {
+	tests := []struct {
+		name      string
+		backoff   Backoff
+		succeedOn int
+		cancel    bool
+		wantCalls int
+		wantErr   string
+	}{
+		{name: "first attempt", backoff: Backoff{Initial: time.Millisecond}, succeedOn: 1, wantCalls: 1},
+		{name: "after retries", backoff: Backoff{Initial: time.Millisecond}, succeedOn: 3, wantCalls: 3},
+		{
+			name:      "max attempts",
+			backoff:   Backoff{Initial: time.Millisecond, MaxAttempts: 3},
+			wantCalls: 3,
+			wantErr:   "gave up after 3 attempts: fail",
+		},
+		{
+			name:      "success on last attempt",
+			backoff:   Backoff{Initial: time.Millisecond, MaxAttempts: 3},
+			succeedOn: 3,
+			wantCalls: 3,
+		},
+		{
+			name:      "deadline",
+			backoff:   Backoff{Initial: time.Hour, Deadline: 20 * time.Millisecond},
+			wantCalls: 1,
+			wantErr:   "gave up after 1 attempts: fail",
+		},
+		{
+			name:      "cancelled context",
+			backoff:   Backoff{Initial: time.Hour},
+			cancel:    true,
+			wantCalls: 1,
+			wantErr:   "gave up after 1 attempts: fail",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				time.AfterFunc(20*time.Millisecond, cancel)
+			}
+			calls := 0
+			err := Do(ctx, tt.backoff, failing(tt.succeedOn, &calls), nil)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !errors.Is(err, errFail) {
+					t.Fatalf("Do() error = %v, want %q wrapping the last error", err, tt.wantErr)
+				}
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("fn called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// задержка удваивается до Max, разброс не больше 20%
+func TestDoBackoff(t *testing.T) {
+	b := Backoff{Initial: time.Millisecond, Max: 4 * time.Millisecond, MaxAttempts: 6}
+	base := []time.Duration{1, 2, 4, 4, 4}
+	var waits []time.Duration
+	calls := 0
+	Do(context.Background(), b, failing(0, &calls), func(attempt int, err error, wait time.Duration) {
+		if attempt != len(waits)+1 || !errors.Is(err, errFail) {
+			t.Errorf("onError(%d, %v), want attempt %d", attempt, err, len(waits)+1)
+		}
+		waits = append(waits, wait)
+	})
+	if len(waits) != len(base) {
+		t.Fatalf("onError called %d times, want %d", len(waits), len(base))
+	}
+	for i, wait := range waits {
+		min := base[i] * time.Millisecond
+		if max := min + min/5; wait < min || wait > max {
+			t.Errorf("wait %d = %v, want in [%v, %v]", i+1, wait, min, max)
+		}
+	}
+}
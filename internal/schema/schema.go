@@ -0,0 +1,117 @@
+package schema
+
+import (
+	"L0WB/internal/models"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+const draft = "https://json-schema.org/draft/2020-12/schema"
+
+// подмножество JSON Schema, которое генерируется из структур моделей
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+}
+
+// тип с фиксированным набором значений, которые попадают в enum схемы
+type enumer interface {
+	JSONSchemaEnum() []any
+}
+
+var (
+	orderOnce   sync.Once
+	orderSchema *Schema
+)
+
+// схема сообщения с заказом, построенная по models.Order
+func Order() *Schema {
+	orderOnce.Do(func() {
+		orderSchema = Generate(models.Order{})
+		orderSchema.Schema = draft
+		orderSchema.ID = "/api/v1/schema/order"
+		orderSchema.Title = "Order"
+	})
+	return orderSchema
+}
+
+// строит схему по Go-типу значения v с учетом json-тегов:
+// поля без omitempty обязательны, лишние поля запрещены
+func Generate(v any) *Schema {
+	return generate(reflect.TypeOf(v))
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func generate(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	s := &Schema{}
+	if e, ok := reflect.Zero(t).Interface().(enumer); ok {
+		s.Enum = e.JSONSchemaEnum()
+	}
+	switch {
+	case t == timeType:
+		s.Type, s.Format = "string", "date-time"
+	case t.Kind() == reflect.String:
+		s.Type = "string"
+	case t.Kind() == reflect.Bool:
+		s.Type = "boolean"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		s.Type = "integer"
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		s.Type = "number"
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		s.Type = "array"
+		s.Items = generate(t.Elem())
+	case t.Kind() == reflect.Struct:
+		s.Type = "object"
+		s.Properties = map[string]*Schema{}
+		closed := false
+		s.AdditionalProperties = &closed
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, omitempty := jsonName(f)
+			if name == "-" {
+				continue
+			}
+			s.Properties[name] = generate(f.Type)
+			if !omitempty {
+				s.Required = append(s.Required, name)
+			}
+		}
+	}
+	return s
+}
+
+func jsonName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "" {
+		return f.Name, false
+	}
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = f.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			return name, true
+		}
+	}
+	return name, false
+}
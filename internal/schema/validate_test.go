@@ -0,0 +1,113 @@
+package schema
+
+import (
+	"L0WB/internal/models"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// документ заказа со всеми обязательными полями в виде map, чтобы его можно было портить
+func orderDocument(t *testing.T) map[string]any {
+	t.Helper()
+	raw, err := json.Marshal(models.Order{OrderUID: "b563feb7b2b84b6test", Items: []models.Item{{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := map[string]any{}
+	if err = json.Unmarshal(raw, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestOrderValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(doc map[string]any)
+		want   []string
+	}{
+		{
+			name:   "valid",
+			modify: func(doc map[string]any) {},
+		},
+		{
+			name:   "optional status set",
+			modify: func(doc map[string]any) { doc["status"] = string(models.StatusCreated) },
+		},
+		{
+			name:   "missing required property",
+			modify: func(doc map[string]any) { delete(doc, "track_number") },
+			want:   []string{"track_number: required property is missing"},
+		},
+		{
+			name:   "unknown property",
+			modify: func(doc map[string]any) { doc["extra"] = 1 },
+			want:   []string{"extra: unknown property"},
+		},
+		{
+			name:   "string instead of integer",
+			modify: func(doc map[string]any) { doc["sm_id"] = "99" },
+			want:   []string{"sm_id: expected integer, got string"},
+		},
+		{
+			name:   "fractional integer",
+			modify: func(doc map[string]any) { doc["sm_id"] = 1.5 },
+			want:   []string{"sm_id: expected integer, got number"},
+		},
+		{
+			name:   "null array",
+			modify: func(doc map[string]any) { doc["items"] = nil },
+			want:   []string{"items: expected array, got null"},
+		},
+		{
+			name:   "invalid date-time",
+			modify: func(doc map[string]any) { doc["date_created"] = "2021-11-26 06:22:19" },
+			want:   []string{"date_created: expected RFC 3339 date-time"},
+		},
+		{
+			name:   "unknown status",
+			modify: func(doc map[string]any) { doc["status"] = "lost" },
+			want:   []string{fmt.Sprintf("status: value is not one of %v", Order().Properties["status"].Enum)},
+		},
+		{
+			name: "nested paths",
+			modify: func(doc map[string]any) {
+				delete(doc["payment"].(map[string]any), "bank")
+				doc["items"].([]any)[0].(map[string]any)["price"] = "1"
+			},
+			want: []string{
+				"items[0].price: expected integer, got string",
+				"payment.bank: required property is missing",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := orderDocument(t)
+			tt.modify(doc)
+			raw, err := json.Marshal(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			violations, err := Order().Validate(raw)
+			if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			var got []string
+			for _, v := range violations {
+				got = append(got, v.Path+": "+v.Message)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("violations = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderValidateInvalidJSON(t *testing.T) {
+	if _, err := Order().Validate([]byte(`{"order_uid":`)); err == nil {
+		t.Error("Validate() of truncated JSON returned no error")
+	}
+}
@@ -0,0 +1,192 @@
+package validation
+
+// валюта по ISO 4217
+type Currency struct {
+	Code    string
+	Numeric string
+	// число знаков после запятой в минимальной единице (копейки, центы)
+	MinorUnits int
+	Name       string
+}
+
+// действующие валюты ISO 4217
+var currencies = map[string]Currency{
+	"AED": {Code: "AED", Numeric: "784", MinorUnits: 2, Name: "UAE Dirham"},
+	"AFN": {Code: "AFN", Numeric: "971", MinorUnits: 2, Name: "Afghani"},
+	"ALL": {Code: "ALL", Numeric: "008", MinorUnits: 2, Name: "Lek"},
+	"AMD": {Code: "AMD", Numeric: "051", MinorUnits: 2, Name: "Armenian Dram"},
+	"ANG": {Code: "ANG", Numeric: "532", MinorUnits: 2, Name: "Netherlands Antillean Guilder"},
+	"AOA": {Code: "AOA", Numeric: "973", MinorUnits: 2, Name: "Kwanza"},
+	"ARS": {Code: "ARS", Numeric: "032", MinorUnits: 2, Name: "Argentine Peso"},
+	"AUD": {Code: "AUD", Numeric: "036", MinorUnits: 2, Name: "Australian Dollar"},
+	"AWG": {Code: "AWG", Numeric: "533", MinorUnits: 2, Name: "Aruban Florin"},
+	"AZN": {Code: "AZN", Numeric: "944", MinorUnits: 2, Name: "Azerbaijan Manat"},
+	"BAM": {Code: "BAM", Numeric: "977", MinorUnits: 2, Name: "Convertible Mark"},
+	"BBD": {Code: "BBD", Numeric: "052", MinorUnits: 2, Name: "Barbados Dollar"},
+	"BDT": {Code: "BDT", Numeric: "050", MinorUnits: 2, Name: "Taka"},
+	"BGN": {Code: "BGN", Numeric: "975", MinorUnits: 2, Name: "Bulgarian Lev"},
+	"BHD": {Code: "BHD", Numeric: "048", MinorUnits: 3, Name: "Bahraini Dinar"},
+	"BIF": {Code: "BIF", Numeric: "108", MinorUnits: 0, Name: "Burundi Franc"},
+	"BMD": {Code: "BMD", Numeric: "060", MinorUnits: 2, Name: "Bermudian Dollar"},
+	"BND": {Code: "BND", Numeric: "096", MinorUnits: 2, Name: "Brunei Dollar"},
+	"BOB": {Code: "BOB", Numeric: "068", MinorUnits: 2, Name: "Boliviano"},
+	"BOV": {Code: "BOV", Numeric: "984", MinorUnits: 2, Name: "Mvdol"},
+	"BRL": {Code: "BRL", Numeric: "986", MinorUnits: 2, Name: "Brazilian Real"},
+	"BSD": {Code: "BSD", Numeric: "044", MinorUnits: 2, Name: "Bahamian Dollar"},
+	"BTN": {Code: "BTN", Numeric: "064", MinorUnits: 2, Name: "Ngultrum"},
+	"BWP": {Code: "BWP", Numeric: "072", MinorUnits: 2, Name: "Pula"},
+	"BYN": {Code: "BYN", Numeric: "933", MinorUnits: 2, Name: "Belarusian Ruble"},
+	"BZD": {Code: "BZD", Numeric: "084", MinorUnits: 2, Name: "Belize Dollar"},
+	"CAD": {Code: "CAD", Numeric: "124", MinorUnits: 2, Name: "Canadian Dollar"},
+	"CDF": {Code: "CDF", Numeric: "976", MinorUnits: 2, Name: "Congolese Franc"},
+	"CHE": {Code: "CHE", Numeric: "947", MinorUnits: 2, Name: "WIR Euro"},
+	"CHF": {Code: "CHF", Numeric: "756", MinorUnits: 2, Name: "Swiss Franc"},
+	"CHW": {Code: "CHW", Numeric: "948", MinorUnits: 2, Name: "WIR Franc"},
+	"CLF": {Code: "CLF", Numeric: "990", MinorUnits: 4, Name: "Unidad de Fomento"},
+	"CLP": {Code: "CLP", Numeric: "152", MinorUnits: 0, Name: "Chilean Peso"},
+	"CNY": {Code: "CNY", Numeric: "156", MinorUnits: 2, Name: "Yuan Renminbi"},
+	"COP": {Code: "COP", Numeric: "170", MinorUnits: 2, Name: "Colombian Peso"},
+	"COU": {Code: "COU", Numeric: "970", MinorUnits: 2, Name: "Unidad de Valor Real"},
+	"CRC": {Code: "CRC", Numeric: "188", MinorUnits: 2, Name: "Costa Rican Colon"},
+	"CUP": {Code: "CUP", Numeric: "192", MinorUnits: 2, Name: "Cuban Peso"},
+	"CVE": {Code: "CVE", Numeric: "132", MinorUnits: 2, Name: "Cabo Verde Escudo"},
+	"CZK": {Code: "CZK", Numeric: "203", MinorUnits: 2, Name: "Czech Koruna"},
+	"DJF": {Code: "DJF", Numeric: "262", MinorUnits: 0, Name: "Djibouti Franc"},
+	"DKK": {Code: "DKK", Numeric: "208", MinorUnits: 2, Name: "Danish Krone"},
+	"DOP": {Code: "DOP", Numeric: "214", MinorUnits: 2, Name: "Dominican Peso"},
+	"DZD": {Code: "DZD", Numeric: "012", MinorUnits: 2, Name: "Algerian Dinar"},
+	"EGP": {Code: "EGP", Numeric: "818", MinorUnits: 2, Name: "Egyptian Pound"},
+	"ERN": {Code: "ERN", Numeric: "232", MinorUnits: 2, Name: "Nakfa"},
+	"ETB": {Code: "ETB", Numeric: "230", MinorUnits: 2, Name: "Ethiopian Birr"},
+	"EUR": {Code: "EUR", Numeric: "978", MinorUnits: 2, Name: "Euro"},
+	"FJD": {Code: "FJD", Numeric: "242", MinorUnits: 2, Name: "Fiji Dollar"},
+	"FKP": {Code: "FKP", Numeric: "238", MinorUnits: 2, Name: "Falkland Islands Pound"},
+	"GBP": {Code: "GBP", Numeric: "826", MinorUnits: 2, Name: "Pound Sterling"},
+	"GEL": {Code: "GEL", Numeric: "981", MinorUnits: 2, Name: "Lari"},
+	"GHS": {Code: "GHS", Numeric: "936", MinorUnits: 2, Name: "Ghana Cedi"},
+	"GIP": {Code: "GIP", Numeric: "292", MinorUnits: 2, Name: "Gibraltar Pound"},
+	"GMD": {Code: "GMD", Numeric: "270", MinorUnits: 2, Name: "Dalasi"},
+	"GNF": {Code: "GNF", Numeric: "324", MinorUnits: 0, Name: "Guinean Franc"},
+	"GTQ": {Code: "GTQ", Numeric: "320", MinorUnits: 2, Name: "Quetzal"},
+	"GYD": {Code: "GYD", Numeric: "328", MinorUnits: 2, Name: "Guyana Dollar"},
+	"HKD": {Code: "HKD", Numeric: "344", MinorUnits: 2, Name: "Hong Kong Dollar"},
+	"HNL": {Code: "HNL", Numeric: "340", MinorUnits: 2, Name: "Lempira"},
+	"HTG": {Code: "HTG", Numeric: "332", MinorUnits: 2, Name: "Gourde"},
+	"HUF": {Code: "HUF", Numeric: "348", MinorUnits: 2, Name: "Forint"},
+	"IDR": {Code: "IDR", Numeric: "360", MinorUnits: 2, Name: "Rupiah"},
+	"ILS": {Code: "ILS", Numeric: "376", MinorUnits: 2, Name: "New Israeli Sheqel"},
+	"INR": {Code: "INR", Numeric: "356", MinorUnits: 2, Name: "Indian Rupee"},
+	"IQD": {Code: "IQD", Numeric: "368", MinorUnits: 3, Name: "Iraqi Dinar"},
+	"IRR": {Code: "IRR", Numeric: "364", MinorUnits: 2, Name: "Iranian Rial"},
+	"ISK": {Code: "ISK", Numeric: "352", MinorUnits: 0, Name: "Iceland Krona"},
+	"JMD": {Code: "JMD", Numeric: "388", MinorUnits: 2, Name: "Jamaican Dollar"},
+	"JOD": {Code: "JOD", Numeric: "400", MinorUnits: 3, Name: "Jordanian Dinar"},
+	"JPY": {Code: "JPY", Numeric: "392", MinorUnits: 0, Name: "Yen"},
+	"KES": {Code: "KES", Numeric: "404", MinorUnits: 2, Name: "Kenyan Shilling"},
+	"KGS": {Code: "KGS", Numeric: "417", MinorUnits: 2, Name: "Som"},
+	"KHR": {Code: "KHR", Numeric: "116", MinorUnits: 2, Name: "Riel"},
+	"KMF": {Code: "KMF", Numeric: "174", MinorUnits: 0, Name: "Comorian Franc"},
+	"KPW": {Code: "KPW", Numeric: "408", MinorUnits: 2, Name: "North Korean Won"},
+	"KRW": {Code: "KRW", Numeric: "410", MinorUnits: 0, Name: "Won"},
+	"KWD": {Code: "KWD", Numeric: "414", MinorUnits: 3, Name: "Kuwaiti Dinar"},
+	"KYD": {Code: "KYD", Numeric: "136", MinorUnits: 2, Name: "Cayman Islands Dollar"},
+	"KZT": {Code: "KZT", Numeric: "398", MinorUnits: 2, Name: "Tenge"},
+	"LAK": {Code: "LAK", Numeric: "418", MinorUnits: 2, Name: "Lao Kip"},
+	"LBP": {Code: "LBP", Numeric: "422", MinorUnits: 2, Name: "Lebanese Pound"},
+	"LKR": {Code: "LKR", Numeric: "144", MinorUnits: 2, Name: "Sri Lanka Rupee"},
+	"LRD": {Code: "LRD", Numeric: "430", MinorUnits: 2, Name: "Liberian Dollar"},
+	"LSL": {Code: "LSL", Numeric: "426", MinorUnits: 2, Name: "Loti"},
+	"LYD": {Code: "LYD", Numeric: "434", MinorUnits: 3, Name: "Libyan Dinar"},
+	"MAD": {Code: "MAD", Numeric: "504", MinorUnits: 2, Name: "Moroccan Dirham"},
+	"MDL": {Code: "MDL", Numeric: "498", MinorUnits: 2, Name: "Moldovan Leu"},
+	"MGA": {Code: "MGA", Numeric: "969", MinorUnits: 2, Name: "Malagasy Ariary"},
+	"MKD": {Code: "MKD", Numeric: "807", MinorUnits: 2, Name: "Denar"},
+	"MMK": {Code: "MMK", Numeric: "104", MinorUnits: 2, Name: "Kyat"},
+	"MNT": {Code: "MNT", Numeric: "496", MinorUnits: 2, Name: "Tugrik"},
+	"MOP": {Code: "MOP", Numeric: "446", MinorUnits: 2, Name: "Pataca"},
+	"MRU": {Code: "MRU", Numeric: "929", MinorUnits: 2, Name: "Ouguiya"},
+	"MUR": {Code: "MUR", Numeric: "480", MinorUnits: 2, Name: "Mauritius Rupee"},
+	"MVR": {Code: "MVR", Numeric: "462", MinorUnits: 2, Name: "Rufiyaa"},
+	"MWK": {Code: "MWK", Numeric: "454", MinorUnits: 2, Name: "Malawi Kwacha"},
+	"MXN": {Code: "MXN", Numeric: "484", MinorUnits: 2, Name: "Mexican Peso"},
+	"MXV": {Code: "MXV", Numeric: "979", MinorUnits: 2, Name: "Mexican Unidad de Inversion"},
+	"MYR": {Code: "MYR", Numeric: "458", MinorUnits: 2, Name: "Malaysian Ringgit"},
+	"MZN": {Code: "MZN", Numeric: "943", MinorUnits: 2, Name: "Mozambique Metical"},
+	"NAD": {Code: "NAD", Numeric: "516", MinorUnits: 2, Name: "Namibia Dollar"},
+	"NGN": {Code: "NGN", Numeric: "566", MinorUnits: 2, Name: "Naira"},
+	"NIO": {Code: "NIO", Numeric: "558", MinorUnits: 2, Name: "Cordoba Oro"},
+	"NOK": {Code: "NOK", Numeric: "578", MinorUnits: 2, Name: "Norwegian Krone"},
+	"NPR": {Code: "NPR", Numeric: "524", MinorUnits: 2, Name: "Nepalese Rupee"},
+	"NZD": {Code: "NZD", Numeric: "554", MinorUnits: 2, Name: "New Zealand Dollar"},
+	"OMR": {Code: "OMR", Numeric: "512", MinorUnits: 3, Name: "Rial Omani"},
+	"PAB": {Code: "PAB", Numeric: "590", MinorUnits: 2, Name: "Balboa"},
+	"PEN": {Code: "PEN", Numeric: "604", MinorUnits: 2, Name: "Sol"},
+	"PGK": {Code: "PGK", Numeric: "598", MinorUnits: 2, Name: "Kina"},
+	"PHP": {Code: "PHP", Numeric: "608", MinorUnits: 2, Name: "Philippine Peso"},
+	"PKR": {Code: "PKR", Numeric: "586", MinorUnits: 2, Name: "Pakistan Rupee"},
+	"PLN": {Code: "PLN", Numeric: "985", MinorUnits: 2, Name: "Zloty"},
+	"PYG": {Code: "PYG", Numeric: "600", MinorUnits: 0, Name: "Guarani"},
+	"QAR": {Code: "QAR", Numeric: "634", MinorUnits: 2, Name: "Qatari Rial"},
+	"RON": {Code: "RON", Numeric: "946", MinorUnits: 2, Name: "Romanian Leu"},
+	"RSD": {Code: "RSD", Numeric: "941", MinorUnits: 2, Name: "Serbian Dinar"},
+	"RUB": {Code: "RUB", Numeric: "643", MinorUnits: 2, Name: "Russian Ruble"},
+	"RWF": {Code: "RWF", Numeric: "646", MinorUnits: 0, Name: "Rwanda Franc"},
+	"SAR": {Code: "SAR", Numeric: "682", MinorUnits: 2, Name: "Saudi Riyal"},
+	"SBD": {Code: "SBD", Numeric: "090", MinorUnits: 2, Name: "Solomon Islands Dollar"},
+	"SCR": {Code: "SCR", Numeric: "690", MinorUnits: 2, Name: "Seychelles Rupee"},
+	"SDG": {Code: "SDG", Numeric: "938", MinorUnits: 2, Name: "Sudanese Pound"},
+	"SEK": {Code: "SEK", Numeric: "752", MinorUnits: 2, Name: "Swedish Krona"},
+	"SGD": {Code: "SGD", Numeric: "702", MinorUnits: 2, Name: "Singapore Dollar"},
+	"SHP": {Code: "SHP", Numeric: "654", MinorUnits: 2, Name: "Saint Helena Pound"},
+	"SLE": {Code: "SLE", Numeric: "925", MinorUnits: 2, Name: "Leone"},
+	"SOS": {Code: "SOS", Numeric: "706", MinorUnits: 2, Name: "Somali Shilling"},
+	"SRD": {Code: "SRD", Numeric: "968", MinorUnits: 2, Name: "Surinam Dollar"},
+	"SSP": {Code: "SSP", Numeric: "728", MinorUnits: 2, Name: "South Sudanese Pound"},
+	"STN": {Code: "STN", Numeric: "930", MinorUnits: 2, Name: "Dobra"},
+	"SVC": {Code: "SVC", Numeric: "222", MinorUnits: 2, Name: "El Salvador Colon"},
+	"SYP": {Code: "SYP", Numeric: "760", MinorUnits: 2, Name: "Syrian Pound"},
+	"SZL": {Code: "SZL", Numeric: "748", MinorUnits: 2, Name: "Lilangeni"},
+	"THB": {Code: "THB", Numeric: "764", MinorUnits: 2, Name: "Baht"},
+	"TJS": {Code: "TJS", Numeric: "972", MinorUnits: 2, Name: "Somoni"},
+	"TMT": {Code: "TMT", Numeric: "934", MinorUnits: 2, Name: "Turkmenistan New Manat"},
+	"TND": {Code: "TND", Numeric: "788", MinorUnits: 3, Name: "Tunisian Dinar"},
+	"TOP": {Code: "TOP", Numeric: "776", MinorUnits: 2, Name: "Pa'anga"},
+	"TRY": {Code: "TRY", Numeric: "949", MinorUnits: 2, Name: "Turkish Lira"},
+	"TTD": {Code: "TTD", Numeric: "780", MinorUnits: 2, Name: "Trinidad and Tobago Dollar"},
+	"TWD": {Code: "TWD", Numeric: "901", MinorUnits: 2, Name: "New Taiwan Dollar"},
+	"TZS": {Code: "TZS", Numeric: "834", MinorUnits: 2, Name: "Tanzanian Shilling"},
+	"UAH": {Code: "UAH", Numeric: "980", MinorUnits: 2, Name: "Hryvnia"},
+	"UGX": {Code: "UGX", Numeric: "800", MinorUnits: 0, Name: "Uganda Shilling"},
+	"USD": {Code: "USD", Numeric: "840", MinorUnits: 2, Name: "US Dollar"},
+	"USN": {Code: "USN", Numeric: "997", MinorUnits: 2, Name: "US Dollar (Next day)"},
+	"UYI": {Code: "UYI", Numeric: "940", MinorUnits: 0, Name: "Uruguay Peso en Unidades Indexadas"},
+	"UYU": {Code: "UYU", Numeric: "858", MinorUnits: 2, Name: "Peso Uruguayo"},
+	"UYW": {Code: "UYW", Numeric: "927", MinorUnits: 4, Name: "Unidad Previsional"},
+	"UZS": {Code: "UZS", Numeric: "860", MinorUnits: 2, Name: "Uzbekistan Sum"},
+	"VED": {Code: "VED", Numeric: "926", MinorUnits: 2, Name: "Bolivar Soberano"},
+	"VES": {Code: "VES", Numeric: "928", MinorUnits: 2, Name: "Bolivar Soberano"},
+	"VND": {Code: "VND", Numeric: "704", MinorUnits: 0, Name: "Dong"},
+	"VUV": {Code: "VUV", Numeric: "548", MinorUnits: 0, Name: "Vatu"},
+	"WST": {Code: "WST", Numeric: "882", MinorUnits: 2, Name: "Tala"},
+	"XAF": {Code: "XAF", Numeric: "950", MinorUnits: 0, Name: "CFA Franc BEAC"},
+	"XCD": {Code: "XCD", Numeric: "951", MinorUnits: 2, Name: "East Caribbean Dollar"},
+	"XOF": {Code: "XOF", Numeric: "952", MinorUnits: 0, Name: "CFA Franc BCEAO"},
+	"XPF": {Code: "XPF", Numeric: "953", MinorUnits: 0, Name: "CFP Franc"},
+	"YER": {Code: "YER", Numeric: "886", MinorUnits: 2, Name: "Yemeni Rial"},
+	"ZAR": {Code: "ZAR", Numeric: "710", MinorUnits: 2, Name: "Rand"},
+	"ZMW": {Code: "ZMW", Numeric: "967", MinorUnits: 2, Name: "Zambian Kwacha"},
+	"ZWG": {Code: "ZWG", Numeric: "924", MinorUnits: 2, Name: "Zimbabwe Gold"},
+	"ZWL": {Code: "ZWL", Numeric: "932", MinorUnits: 2, Name: "Zimbabwe Dollar"},
+}
+
+// ищет валюту по трехбуквенному коду в верхнем регистре, как его пишет ISO 4217;
+// коды в другом регистре и с пробелами не принимаются, как и в ValidCurrency
+func LookupCurrency(code string) (Currency, bool) {
+	c, ok := currencies[code]
+	return c, ok
+}
+
+func ValidCurrency(code string) bool {
+	_, ok := LookupCurrency(code)
+	return ok
+}
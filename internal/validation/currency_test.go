@@ -0,0 +1,36 @@
+package validation
+
+import "testing"
+
+func TestCurrency(t *testing.T) {
+	tests := []struct {
+		code       string
+		want       bool
+		minorUnits int
+	}{
+		{code: "USD", want: true, minorUnits: 2},
+		{code: "RUB", want: true, minorUnits: 2},
+		{code: "JPY", want: true, minorUnits: 0},
+		{code: "BHD", want: true, minorUnits: 3},
+		{code: "usd"},
+		{code: "Usd"},
+		{code: " USD"},
+		{code: "XYZ"},
+		{code: "US"},
+		{code: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			if got := ValidCurrency(tt.code); got != tt.want {
+				t.Errorf("ValidCurrency(%q) = %v, want %v", tt.code, got, tt.want)
+			}
+			c, ok := LookupCurrency(tt.code)
+			if ok != tt.want {
+				t.Fatalf("LookupCurrency(%q) ok = %v, want %v", tt.code, ok, tt.want)
+			}
+			if ok && (c.Code != tt.code || c.MinorUnits != tt.minorUnits) {
+				t.Errorf("LookupCurrency(%q) = %+v, want code %s with %d minor units", tt.code, c, tt.code, tt.minorUnits)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// проверяет адрес вида local@domain без отображаемого имени;
+// домен должен состоять минимум из двух корректных меток
+func ParseEmail(s string) (string, error) {
+	addr, err := mail.ParseAddress(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid email: %w", err)
+	}
+	if addr.Name != "" || addr.Address != strings.TrimSpace(s) {
+		return "", fmt.Errorf("email must be a bare address without display name")
+	}
+	at := strings.LastIndex(addr.Address, "@")
+	local, domain := addr.Address[:at], addr.Address[at+1:]
+	if len(local) > 64 || len(addr.Address) > 254 {
+		return "", fmt.Errorf("email is too long")
+	}
+	if err := validDomain(domain); err != nil {
+		return "", err
+	}
+	return addr.Address, nil
+}
+
+func ValidEmail(s string) bool {
+	_, err := ParseEmail(s)
+	return err == nil
+}
+
+func validDomain(domain string) error {
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return fmt.Errorf("email domain must contain a dot")
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 {
+			return fmt.Errorf("invalid email domain %q", domain)
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("invalid email domain %q", domain)
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r > 127) {
+				return fmt.Errorf("invalid email domain %q", domain)
+			}
+		}
+	}
+	return nil
+}
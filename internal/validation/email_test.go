@@ -0,0 +1,38 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidEmail(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"test@gmail.com", true},
+		{"first.last+tag@sub.example.co", true},
+		{"user@xn--80ak6aa92e.com", true},
+		{"user@пример.рф", true},
+		{strings.Repeat("a", 64) + "@example.com", true},
+		{strings.Repeat("a", 65) + "@example.com", false},
+		{"a@" + strings.Repeat("b", 63) + ".com", true},
+		{"a@" + strings.Repeat("b", 64) + ".com", false},
+		{"Test <test@gmail.com>", false},
+		{" test@gmail.com ", true},
+		{"test@localhost", false},
+		{"test@-example.com", false},
+		{"test@example-.com", false},
+		{"test@example..com", false},
+		{"test@exa_mple.com", false},
+		{"test.gmail.com", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := ValidEmail(tt.in); got != tt.want {
+				t.Errorf("ValidEmail(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
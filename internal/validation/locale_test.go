@@ -0,0 +1,26 @@
+package validation
+
+import "testing"
+
+func TestValidLocale(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"en", true},
+		{"ru", true},
+		{"zu", true},
+		{"EN", false},
+		{"en-US", false},
+		{"eng", false},
+		{"xx", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			if got := ValidLocale(tt.code); got != tt.want {
+				t.Errorf("ValidLocale(%q) = %v, want %v", tt.code, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// разделители, допустимые при вводе номера и отбрасываемые при нормализации
+const phoneSeparators = " -().\t"
+
+// приводит номер к формату E.164 (+ и от 8 до 15 цифр, первая не 0)
+func ParsePhone(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "+") {
+		return "", fmt.Errorf("phone must start with + and country code")
+	}
+	var b strings.Builder
+	b.WriteByte('+')
+	for _, r := range s[1:] {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case strings.ContainsRune(phoneSeparators, r):
+		default:
+			return "", fmt.Errorf("phone contains invalid character %q", r)
+		}
+	}
+	normalized := b.String()
+	digits := len(normalized) - 1
+	if digits < 8 || digits > 15 {
+		return "", fmt.Errorf("phone must contain 8 to 15 digits, got %d", digits)
+	}
+	if normalized[1] == '0' {
+		return "", fmt.Errorf("country code cannot start with 0")
+	}
+	return normalized, nil
+}
+
+func ValidPhone(s string) bool {
+	_, err := ParsePhone(s)
+	return err == nil
+}
@@ -0,0 +1,38 @@
+package validation
+
+import "testing"
+
+func TestParsePhone(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "+79161234567", want: "+79161234567"},
+		{in: " +7 (916) 123-45.67 ", want: "+79161234567"},
+		{in: "+12345678", want: "+12345678"},
+		{in: "+123456789012345", want: "+123456789012345"},
+		{in: "+1234567", wantErr: true},
+		{in: "+1234567890123456", wantErr: true},
+		{in: "79161234567", wantErr: true},
+		{in: "+0123456789", wantErr: true},
+		{in: "+7916123456a", wantErr: true},
+		{in: "+7+9161234567", wantErr: true},
+		{in: "+", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParsePhone(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePhone(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePhone(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if ValidPhone(tt.in) == tt.wantErr {
+				t.Errorf("ValidPhone(%q) = %v, want %v", tt.in, !tt.wantErr, tt.wantErr)
+			}
+		})
+	}
+}
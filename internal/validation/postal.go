@@ -0,0 +1,65 @@
+package validation
+
+import (
+	"regexp"
+	"strings"
+)
+
+// шаблоны почтовых индексов по коду страны ISO 3166-1
+var postalPatterns = map[string]*regexp.Regexp{
+	"RU": regexp.MustCompile(`^\d{6}$`),
+	"BY": regexp.MustCompile(`^\d{6}$`),
+	"KZ": regexp.MustCompile(`^(\d{6}|[A-Z]\d{2}[A-Z]\d[A-Z]\d)$`),
+	"KG": regexp.MustCompile(`^\d{6}$`),
+	"UZ": regexp.MustCompile(`^\d{6}$`),
+	"TJ": regexp.MustCompile(`^\d{6}$`),
+	"AM": regexp.MustCompile(`^\d{4}$`),
+	"AZ": regexp.MustCompile(`^(AZ)?\s?\d{4}$`),
+	"GE": regexp.MustCompile(`^\d{4}$`),
+	"IL": regexp.MustCompile(`^\d{5}(\d{2})?$`),
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"PL": regexp.MustCompile(`^\d{2}-\d{3}$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]?\s?\d[A-Z]{2}$`),
+	"CN": regexp.MustCompile(`^\d{6}$`),
+}
+
+// названия регионов, которые сопоставляются с кодом страны
+var regionAliases = map[string]string{
+	"russia":     "RU",
+	"россия":     "RU",
+	"belarus":    "BY",
+	"беларусь":   "BY",
+	"kazakhstan": "KZ",
+	"казахстан":  "KZ",
+	"kyrgyzstan": "KG",
+	"киргизия":   "KG",
+	"uzbekistan": "UZ",
+	"узбекистан": "UZ",
+	"armenia":    "AM",
+	"армения":    "AM",
+	"israel":     "IL",
+	"израиль":    "IL",
+}
+
+// индекс для неизвестных регионов: 2-10 букв и цифр, допускаются пробел и дефис
+var genericPostal = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 \-]{0,8}[A-Za-z0-9]$`)
+
+// проверяет почтовый индекс по шаблону региона; для неизвестного региона
+// применяется общая проверка формата
+func ValidPostalCode(region, zip string) bool {
+	zip = strings.ToUpper(strings.TrimSpace(zip))
+	if re, ok := postalPatterns[regionCode(region)]; ok {
+		return re.MatchString(zip)
+	}
+	return genericPostal.MatchString(zip)
+}
+
+func regionCode(region string) string {
+	region = strings.TrimSpace(region)
+	if code, ok := regionAliases[strings.ToLower(region)]; ok {
+		return code
+	}
+	return strings.ToUpper(region)
+}
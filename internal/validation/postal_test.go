@@ -0,0 +1,36 @@
+package validation
+
+import "testing"
+
+func TestValidPostalCode(t *testing.T) {
+	tests := []struct {
+		region string
+		zip    string
+		want   bool
+	}{
+		{"RU", "123456", true},
+		{"Россия", "123456", true},
+		{" russia ", "123456", true},
+		{"RU", "12345", false},
+		{"RU", "1234567", false},
+		{"KZ", "A15C3E4", true},
+		{"kz", "a15c3e4", true},
+		{"US", "12345-6789", true},
+		{"US", "12345-678", false},
+		{"GB", "SW1A 1AA", true},
+		{"PL", "00-950", true},
+		{"PL", "00950", false},
+		{"Kraiot", "2639809", true},
+		{"", "AB-12", true},
+		{"", "1", false},
+		{"", "12345678901", false},
+		{"", "-1234", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.region+"/"+tt.zip, func(t *testing.T) {
+			if got := ValidPostalCode(tt.region, tt.zip); got != tt.want {
+				t.Errorf("ValidPostalCode(%q, %q) = %v, want %v", tt.region, tt.zip, got, tt.want)
+			}
+		})
+	}
+}